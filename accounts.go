@@ -0,0 +1,247 @@
+package poloniex
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// wallets are the account names TransferBalance moves funds between
+var wallets = map[string]bool{"exchange": true, "margin": true, "lending": true}
+
+type (
+	// AccountManager holds a client per sub-account, all sharing the events, rate limit, middleware
+	// and public websocket of one public client, so only that client dials and fetches markets.
+	// Subscribe through the public client, events from its websocket reach listeners added through any account.
+	// Account clients have no websocket of their own, so cannot use SubscribeAccount, and leave ByID and ByName
+	// empty, use the lookups of Public instead. SetRateLimit and Use on the public client apply to every account,
+	// even ones added earlier, middleware an account adds with Use runs after the public client's.
+	AccountManager struct {
+		public   *Poloniex
+		mutex    sync.RWMutex
+		accounts map[string]*Poloniex
+	}
+
+	// ManagedBalances is the result of AccountManager.Balances
+	ManagedBalances struct {
+		ByAccount map[string]Balances
+		// Total sums every account's available and on order amounts per currency
+		Total Balances
+	}
+
+	// AccountTransfer is a transfer between the wallets of one account
+	AccountTransfer struct {
+		Account  string
+		Currency string
+		Amount   float64
+		From     string
+		To       string
+	}
+
+	// AccountTransferResult is the outcome of an AccountTransfer
+	AccountTransferResult struct {
+		AccountTransfer
+		Response TransferBalance
+		Err      error
+	}
+)
+
+// NewAccountManager creates an account manager sharing the markets and websocket of public, for example NewPublicOnly()
+func NewAccountManager(public *Poloniex) *AccountManager {
+	return &AccountManager{public: public, accounts: map[string]*Poloniex{}}
+}
+
+// Public returns the shared public client
+func (m *AccountManager) Public() *Poloniex {
+	return m.public
+}
+
+// Add adds an account taking its credentials from provider, replacing any account of the same name
+func (m *AccountManager) Add(name string, provider CredentialProvider) (*Poloniex, error) {
+	c, err := provider.Credentials()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading credentials for "+name+" failed")
+	}
+	p := m.AddCredentials(name, c.Key, c.Secret)
+	p.credMutex.Lock()
+	p.provider = provider
+	p.credMutex.Unlock()
+	return p, nil
+}
+
+// AddCredentials adds an account with a key and secret, replacing any account of the same name
+func (m *AccountManager) AddCredentials(name, key, secret string) *Poloniex {
+	p := newClient()
+	p.SetCredentials(Credentials{Key: key, Secret: secret})
+	p.emitter = m.public.emitter
+	p.logger = m.public.logger
+	p.metrics = m.public.metrics
+	p.tracer = m.public.tracer
+	// every account is behind the same IP, so waits for the public client's rate limiter,
+	// which is read at each request along with its middleware
+	p.shared = m.public
+	p.limiter = nil
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.accounts[name] = p
+	return p
+}
+
+// Remove removes an account
+func (m *AccountManager) Remove(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.accounts, name)
+}
+
+// Account returns the client of an account
+func (m *AccountManager) Account(name string) (*Poloniex, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	p, ok := m.accounts[name]
+	return p, ok
+}
+
+// Names returns the names of the accounts, sorted
+func (m *AccountManager) Names() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	names := make([]string, 0, len(m.accounts))
+	for name := range m.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// each calls fn for every account concurrently, returning the first error in name order
+func (m *AccountManager) each(fn func(name string, p *Poloniex) error) error {
+	names := m.Names()
+	errs := make([]error, len(names))
+	wg := sync.WaitGroup{}
+	for i, name := range names {
+		p, ok := m.Account(name)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string, p *Poloniex) {
+			defer wg.Done()
+			if err := fn(name, p); err != nil {
+				errs[i] = errors.Wrap(err, name)
+			}
+		}(i, name, p)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Balances returns the balances of every account and their total.
+// On error the accounts which succeeded are still returned.
+func (m *AccountManager) Balances() (ab ManagedBalances, err error) {
+	mutex := sync.Mutex{}
+	ab = ManagedBalances{ByAccount: map[string]Balances{}, Total: Balances{}}
+	err = m.each(func(name string, p *Poloniex) error {
+		b, err := p.Balances()
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		ab.ByAccount[name] = b
+		for currency, v := range b {
+			t := ab.Total[currency]
+			t.Available += v.Available
+			t.OnOrders += v.OnOrders
+			t.BTCValue += v.BTCValue
+			ab.Total[currency] = t
+		}
+		return nil
+	})
+	return
+}
+
+// OpenOrders returns the open orders of every account in every market
+func (m *AccountManager) OpenOrders() (orders map[string]OpenOrdersAll, err error) {
+	mutex := sync.Mutex{}
+	orders = map[string]OpenOrdersAll{}
+	err = m.each(func(name string, p *Poloniex) error {
+		o, err := p.OpenOrdersAll()
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		orders[name] = o
+		return nil
+	})
+	return
+}
+
+// MarginPositions returns the open margin positions of every account, by account then pair
+func (m *AccountManager) MarginPositions() (positions map[string]map[string]MarginPosition, err error) {
+	mutex := sync.Mutex{}
+	positions = map[string]map[string]MarginPosition{}
+	err = m.each(func(name string, p *Poloniex) error {
+		all, err := p.MarginPositionAll()
+		if err != nil {
+			return err
+		}
+		open := map[string]MarginPosition{}
+		for pair, pos := range all {
+			if pos.Type == "long" || pos.Type == "short" {
+				open[pair] = pos
+			}
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		positions[name] = open
+		return nil
+	})
+	return
+}
+
+// Transfer moves funds between the wallets of an account, checking first that the amount is available
+func (m *AccountManager) Transfer(t AccountTransfer) (tb TransferBalance, err error) {
+	p, ok := m.Account(t.Account)
+	if !ok {
+		return tb, errors.New("unknown account " + t.Account)
+	}
+	if !wallets[t.From] || !wallets[t.To] || t.From == t.To {
+		return tb, errors.Errorf("cannot transfer from %q to %q", t.From, t.To)
+	}
+	if t.Amount <= 0 {
+		return tb, errors.New("transfer amount must be positive")
+	}
+	available, err := p.AvailableAccountBalances()
+	if err != nil {
+		return tb, errors.Wrap(err, "fetching available balances failed")
+	}
+	wallet := map[string]map[string]float64{
+		"exchange": available.Exchange, "margin": available.Margin, "lending": available.Lending,
+	}[t.From]
+	if wallet[t.Currency] < t.Amount {
+		return tb, errors.Errorf("only %.8f %s available in the %s wallet of %s", wallet[t.Currency], t.Currency, t.From, t.Account)
+	}
+	tb, err = p.TransferBalance(t.Currency, t.Amount, t.From, t.To)
+	if err == nil && tb.Success != 1 {
+		err = errors.New(tb.Message)
+	}
+	return
+}
+
+// TransferAll makes transfers in order, carrying on past failures, and returns the outcome of each
+func (m *AccountManager) TransferAll(transfers []AccountTransfer) []AccountTransferResult {
+	results := make([]AccountTransferResult, len(transfers))
+	for i, t := range transfers {
+		results[i].AccountTransfer = t
+		results[i].Response, results[i].Err = m.Transfer(t)
+	}
+	return results
+}
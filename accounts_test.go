@@ -0,0 +1,31 @@
+package poloniex
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleAccountManager() {
+	public := newClient() // offline, NewPublicOnly would dial and fetch the markets
+	m := NewAccountManager(public)
+	m.AddCredentials("alice", "key-a", "secret-a")
+	m.AddCredentials("bob", "key-b", "secret-b")
+
+	// middleware and rate limits set on the public client after the accounts were added still apply to them
+	public.SetRateLimit(1000, 1000)
+	public.Use(answer(map[string]string{
+		"returnCompleteBalances": `{"BTC":{"available":"1.5","onOrders":"0.5","btcValue":"2"}}`,
+	}))
+	ab, err := m.Balances()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, name := range m.Names() {
+		fmt.Println(name, ab.ByAccount[name]["BTC"].Available)
+	}
+	fmt.Println("total", ab.Total["BTC"].Available, ab.Total["BTC"].OnOrders)
+	// Output:
+	// alice 1.5
+	// bob 1.5
+	// total 3 1
+}
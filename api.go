@@ -3,11 +3,13 @@ package poloniex
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"math"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,17 +20,39 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ErrDryRun is returned by every REST request of a client in dry run mode, see DryRun
+var ErrDryRun = errors.New("dry run, request not sent")
+
 type (
 	// Poloniex describes the API
 	Poloniex struct {
-		Key           string
-		Secret        string
+		credentials   Credentials
+		provider      CredentialProvider
+		credMutex     sync.RWMutex
 		ws            recws.RecConn
 		debug         bool
-		nonce         int64
-		mutex         sync.Mutex
+		nonces        NonceSource
 		emitter       *emission.Emitter
+		listeners     listeners
 		subscriptions map[string]bool
+		recorder      *Recorder
+		recMutex      sync.Mutex
+		books         map[string]*LiveBook
+		tracker       *OrderTracker
+		limiter       *RateLimiter
+		halted        string
+		withdrawals   *WithdrawalGuard
+		noWithdrawals bool
+		dryRun        io.Writer
+		logger        Logger
+		metrics       Metrics
+		tracer        Tracer
+		middleware    []Middleware
+		shared        *Poloniex // the public client an account client reads its rate limiter and middleware from
+		sequences     map[string]int64
+		seqMutex      sync.Mutex
+		haltMutex     sync.RWMutex
+		booksMutex    sync.Mutex
 		ByID          map[string]string
 		ByName        map[string]string
 	}
@@ -44,79 +68,127 @@ const (
 	PUBLICURI = "https://poloniex.com/public"
 	// PRIVATEURI is the address of the public API on Poloniex
 	PRIVATEURI = "https://poloniex.com/tradingApi"
+
+	// timeLayout is the layout of the date strings returned by the REST API, always in UTC
+	timeLayout = "2006-01-02 15:04:05"
 )
 
-// Debug turns on debugmode, which basically dumps all responses from the poloniex API REST server
+// Debug turns on debugmode, which logs every response from the poloniex API REST server at debug level.
+// Without a logger given by WithLogger, it logs to stderr.
 func (p *Poloniex) Debug() {
 	p.debug = true
+	if _, ok := p.logger.(nopLogger); ok {
+		p.logger = NewTextLogger(os.Stderr, LevelDebug)
+	}
 }
 
-func (p *Poloniex) getNonce() string {
-	p.nonce++
-	return fmt.Sprintf("%d", p.nonce)
+// DryRun makes the client write REST requests to w, signed but with the key shortened, instead of sending them.
+// Every request then fails with ErrDryRun. It should be called before the client is in use.
+func (p *Poloniex) DryRun(w io.Writer) {
+	p.dryRun = w
 }
 
-// NewWithCredentials allows to pass in the key and secret directly
-func NewWithCredentials(key, secret string) *Poloniex {
-	p := &Poloniex{}
-	p.Key = key
-	p.Secret = secret
-	p.nonce = time.Now().UnixNano()
-	p.mutex = sync.Mutex{}
-	p.emitter = emission.NewEmitter()
-	p.subscriptions = map[string]bool{}
-	p.ws = recws.RecConn{}
+// SetRateLimit changes how many REST requests the client makes per second, with up to burst at once.
+// The default is the exchange's limit of 6 a second, set it lower when sharing an IP with other clients.
+// It should be called before the client is in use.
+func (p *Poloniex) SetRateLimit(perSecond float64, burst int) {
+	p.limiter = NewRateLimiter(perSecond, burst)
+}
+
+// NewWithCredentials allows to pass in the key and secret directly.
+// Failing to fetch the markets stops the program, use NewWithProvider to handle the error.
+func NewWithCredentials(key, secret string, options ...Option) *Poloniex {
+	p := newClient(options...)
+	p.SetCredentials(Credentials{Key: key, Secret: secret})
 	p.ws.Dial(apiURL, http.Header{})
 
-	p.getMarkets()
+	p.fatal(p.getMarkets())
 
 	return p
 }
 
-// NewWithConfig is the replacement function for New, pass in a configfile to use
-func NewWithConfig(configfile string) *Poloniex {
-	p := map[string]string{}
-	// we have a configfile
-	b, err := ioutil.ReadFile(configfile)
-	if err != nil {
-		log.Fatalln(errors.Wrap(err, "reading "+configfile+" failed."))
-	}
-	err = json.Unmarshal(b, &p)
+// NewWithConfig is the replacement function for New, pass in a configfile to use.
+// Failing to read the config or fetch the markets stops the program, use NewWithProvider to handle the error.
+func NewWithConfig(configfile string, options ...Option) *Poloniex {
+	p, err := NewWithProvider(FileCredentials{Filename: configfile}, options...)
 	if err != nil {
-		log.Fatalln(errors.Wrap(err, "unmarshal of config failed."))
+		newClient(options...).fatal(err)
 	}
-	return NewWithCredentials(p["key"], p["secret"])
+	return p
 }
 
-// NewPublicOnly allows the use of the public and websocket api only
-func NewPublicOnly() *Poloniex {
+// NewREST creates a client for the REST API only, without dialing the websocket or fetching the markets.
+// Pass empty credentials for the public API alone.
+func NewREST(key, secret string, options ...Option) *Poloniex {
+	p := newClient(options...)
+	p.SetCredentials(Credentials{Key: key, Secret: secret})
+	return p
+}
+
+// NewPublicOnly allows the use of the public and websocket api only, failing to fetch the markets stops the program
+func NewPublicOnly(options ...Option) *Poloniex {
+	p := newClient(options...)
+	p.ws.Dial(apiURL, http.Header{})
+	p.fatal(p.getMarkets())
+	return p
+}
+
+// newClient sets up the internal state shared by all constructors, without touching the network
+func newClient(options ...Option) *Poloniex {
 	p := &Poloniex{}
-	p.nonce = time.Now().UnixNano()
-	p.mutex = sync.Mutex{}
+	p.logger = nopLogger{}
+	p.metrics = nopMetrics{}
+	p.tracer = nopTracer{}
+	p.sequences = map[string]int64{}
+	p.nonces = NewCounterNonce()
 	p.emitter = emission.NewEmitter()
 	p.subscriptions = map[string]bool{}
-	p.ws = recws.RecConn{}
-	p.ws.Dial(apiURL, http.Header{})
-	p.getMarkets()
+	p.books = map[string]*LiveBook{}
+	p.limiter = NewRateLimiter(6, 6)
+	// recws logs to the global logger unless told not to
+	p.ws = recws.RecConn{NonVerbose: true}
+	for _, option := range options {
+		option(p)
+	}
 	return p
 }
 
 // New is the legacy way to create a new client, here just to maintain api
-func New(configfile string) *Poloniex {
-	return NewWithConfig(configfile)
+func New(configfile string, options ...Option) *Poloniex {
+	return NewWithConfig(configfile, options...)
 }
 
-func (p *Poloniex) getMarkets() {
+// getMarkets fetches the markets for the channel lookups
+func (p *Poloniex) getMarkets() error {
 	markets, err := p.Ticker()
 	if err != nil {
-		log.Fatalln("error getting markets for lookups", err)
+		return errors.Wrap(err, "fetching markets for lookups failed")
 	}
-	ByName := map[string]string{}
 	ByID := map[string]string{}
 	for k, v := range markets {
-		id := fmt.Sprintf("%d", v.ID)
-		ByName[k] = id
-		ByID[id] = k
+		ByID[fmt.Sprintf("%d", v.ID)] = k
+	}
+	p.setMarkets(ByID)
+	return nil
+}
+
+// fatal stops the program on err, as the constructors without an error to return always have.
+// It goes to the client's logger first, but does not depend on one being configured.
+func (p *Poloniex) fatal(err error) {
+	if err == nil {
+		return
+	}
+	p.logger.Error("creating client failed", "error", err)
+	log.Fatalln(err)
+}
+
+// setMarkets builds the channel lookups from a map of market id to pair name
+func (p *Poloniex) setMarkets(markets map[string]string) {
+	ByName := map[string]string{}
+	ByID := map[string]string{}
+	for id, pair := range markets {
+		ByName[pair] = id
+		ByID[id] = pair
 	}
 
 	ByID["1001"] = "trollbox"
@@ -133,15 +205,6 @@ func (p *Poloniex) getMarkets() {
 	p.ByName = ByName
 }
 
-func trace(s string) (string, time.Time) {
-	return s, time.Now()
-}
-
-func un(s string, startTime time.Time) {
-	elapsed := time.Since(startTime)
-	log.Printf("trace end: %s, elapsed %f secs\n", s, elapsed.Seconds())
-}
-
 func toFloat(i interface{}) float64 {
 	maxFloat := float64(math.MaxFloat64)
 	switch i := i.(type) {
@@ -178,3 +241,17 @@ func toString(i interface{}) string {
 	}
 	return ""
 }
+
+// splitPair splits a pair such as USDT_BTC into the currency it is priced in (USDT) and the currency traded (BTC)
+func splitPair(pair string) (base, quote string) {
+	parts := strings.SplitN(pair, "_", 2)
+	if len(parts) != 2 {
+		return pair, ""
+	}
+	return parts[0], parts[1]
+}
+
+// parseTime parses a date string returned by the REST API
+func parseTime(s string) (time.Time, error) {
+	return time.ParseInLocation(timeLayout, s, time.UTC)
+}
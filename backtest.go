@@ -0,0 +1,510 @@
+package poloniex
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chuckpreslar/emission"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Trader is the part of the client a strategy trades against,
+	// it is satisfied by both *Poloniex and *Backtest so the same strategy can run live or historically
+	Trader interface {
+		Buy(pair string, rate, amount float64) (Buy, error)
+		Sell(pair string, rate, amount float64) (Sell, error)
+		CancelOrder(orderNumber int64) (bool, error)
+		OpenOrders(pair string) (OpenOrders, error)
+		Balances() (Balances, error)
+		On(event interface{}, listener interface{}) *emission.Emitter
+		Off(event interface{}, listener interface{}) *emission.Emitter
+	}
+
+	// BacktestConfig describes the simulated account a backtest starts with
+	BacktestConfig struct {
+		// Balances are the starting balances by currency
+		Balances map[string]float64
+		// Fees is the fee schedule applied to fills, MakerFee for resting orders and TakerFee for crossing orders
+		Fees FeeInfo
+		// Latency is the delay between placing an order and it reaching the simulated market
+		Latency time.Duration
+		// Slippage is the fraction by which taker fills are worsened against the market price
+		Slippage float64
+		// Currency is the currency the equity curve is measured in, defaults to BTC
+		Currency string
+	}
+
+	// Backtest is a simulated exchange which drives a strategy with historical data
+	Backtest struct {
+		config      BacktestConfig
+		mutex       sync.Mutex
+		emitter     *emission.Emitter
+		now         time.Time
+		balances    map[string]float64
+		onOrders    map[string]float64
+		orders      []*backtestOrder
+		nextID      int64
+		nextTradeID int64
+		last        map[string]float64
+		trades      []BacktestTrade
+		equity      []EquityPoint
+		start       float64
+		turnover    float64
+	}
+
+	backtestOrder struct {
+		OpenOrder
+		pair    string
+		active  time.Time
+		checked bool
+	}
+
+	backtestTick struct {
+		pair   string
+		ts     time.Time
+		open   float64
+		high   float64
+		low    float64
+		close  float64
+		volume float64
+	}
+
+	// EquityPoint is the value of the simulated account at a point in time
+	EquityPoint struct {
+		TS     time.Time
+		Equity float64
+	}
+
+	// BacktestTrade is a single simulated fill
+	BacktestTrade struct {
+		TS          time.Time
+		OrderNumber int64
+		TradeID     int64
+		Pair        string
+		Type        string
+		Rate        float64
+		Amount      float64
+		Total       float64
+		Fee         float64
+		FeeCurrency string
+		Maker       bool
+	}
+
+	// BacktestReport summarises the result of a backtest, monetary values are in BacktestConfig.Currency
+	BacktestReport struct {
+		Start       time.Time
+		End         time.Time
+		StartEquity float64
+		EndEquity   float64
+		Return      float64
+		MaxDrawdown float64
+		Sharpe      float64
+		Turnover    float64
+		Equity      []EquityPoint
+		Trades      []BacktestTrade
+		Balances    map[string]float64
+	}
+)
+
+var (
+	_ Trader = (*Poloniex)(nil)
+	_ Trader = (*Backtest)(nil)
+)
+
+// NewBacktest creates a simulated exchange with the starting balances and fees given in config
+func NewBacktest(config BacktestConfig) *Backtest {
+	if config.Currency == "" {
+		config.Currency = "BTC"
+	}
+	b := &Backtest{
+		config:   config,
+		emitter:  emission.NewEmitter(),
+		balances: map[string]float64{},
+		onOrders: map[string]float64{},
+		last:     map[string]float64{},
+		nextID:   1,
+	}
+	for k, v := range config.Balances {
+		b.balances[k] = v
+	}
+	return b
+}
+
+// On adds a listener to a specific event, events are named exactly as they are by the websocket client
+func (b *Backtest) On(event interface{}, listener interface{}) *emission.Emitter {
+	return b.emitter.On(event, listener)
+}
+
+// Off removes a listener for an event
+func (b *Backtest) Off(event interface{}, listener interface{}) *emission.Emitter {
+	return b.emitter.Off(event, listener)
+}
+
+// Now returns the current simulated time
+func (b *Backtest) Now() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.now
+}
+
+// Buy places a simulated limit buy order, it reaches the market after the configured latency
+func (b *Backtest) Buy(pair string, rate, amount float64) (buy Buy, err error) {
+	buy.OrderNumber, err = b.place(pair, "buy", rate, amount)
+	return
+}
+
+// Sell places a simulated limit sell order, it reaches the market after the configured latency
+func (b *Backtest) Sell(pair string, rate, amount float64) (sell Sell, err error) {
+	sell.OrderNumber, err = b.place(pair, "sell", rate, amount)
+	return
+}
+
+func (b *Backtest) place(pair, typ string, rate, amount float64) (int64, error) {
+	if rate <= 0 || amount <= 0 {
+		return 0, errors.New("Invalid rate or amount.")
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	base, quote := splitPair(pair)
+	currency, reserve := base, rate*amount
+	if typ == "sell" {
+		currency, reserve = quote, amount
+	}
+	if b.balances[currency] < reserve {
+		return 0, errors.Errorf("Not enough %s.", currency)
+	}
+	b.balances[currency] -= reserve
+	b.onOrders[currency] += reserve
+	o := &backtestOrder{
+		OpenOrder: OpenOrder{
+			OrderNumber:    b.nextID,
+			Type:           typ,
+			Rate:           rate,
+			StartingAmount: amount,
+			Amount:         amount,
+			Total:          rate * amount,
+			Date:           b.now.UTC().Format(timeLayout),
+		},
+		pair:   pair,
+		active: b.now.Add(b.config.Latency),
+	}
+	b.nextID++
+	b.orders = append(b.orders, o)
+	return o.OrderNumber, nil
+}
+
+// CancelOrder cancels a simulated order, returning the reserved funds
+func (b *Backtest) CancelOrder(orderNumber int64) (success bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, o := range b.orders {
+		if o.OrderNumber != orderNumber {
+			continue
+		}
+		b.release(o)
+		b.orders = append(b.orders[:i], b.orders[i+1:]...)
+		return true, nil
+	}
+	return false, errors.New("Invalid order number, or you are not the person who placed the order.")
+}
+
+// OpenOrders returns the simulated open orders for a given market
+func (b *Backtest) OpenOrders(pair string) (openOrders OpenOrders, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	openOrders = OpenOrders{}
+	for _, o := range b.orders {
+		if o.pair == pair {
+			openOrders = append(openOrders, o.OpenOrder)
+		}
+	}
+	return
+}
+
+// Balances returns the simulated balances, BTCValue is left empty
+func (b *Backtest) Balances() (balances Balances, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	balances = Balances{}
+	for k, v := range b.balances {
+		balances[k] = Balance{Available: v, OnOrders: b.onOrders[k]}
+	}
+	for k, v := range b.onOrders {
+		if _, ok := balances[k]; !ok {
+			balances[k] = Balance{OnOrders: v}
+		}
+	}
+	return
+}
+
+// RunTrades drives the strategy with a market's trade history, each trade is emitted as a websocket trade event
+func (b *Backtest) RunTrades(pair string, tradeHistory TradeHistory) error {
+	type timedTrade struct {
+		ts time.Time
+		TradeHistoryEntry
+	}
+	trades := make([]timedTrade, 0, len(tradeHistory))
+	for _, v := range tradeHistory {
+		ts, err := parseTime(v.Date)
+		if err != nil {
+			return errors.Wrap(err, "parsing trade date failed")
+		}
+		trades = append(trades, timedTrade{ts, v})
+	}
+	// the API returns trades newest first
+	sort.SliceStable(trades, func(i, j int) bool {
+		if trades[i].ts.Equal(trades[j].ts) {
+			return trades[i].TradeID < trades[j].TradeID
+		}
+		return trades[i].ts.Before(trades[j].ts)
+	})
+	for _, v := range trades {
+		tick := backtestTick{pair: pair, ts: v.ts, open: v.Rate, high: v.Rate, low: v.Rate, close: v.Rate, volume: v.Amount}
+		b.step(tick)
+		b.emit(WSOrderbook{Pair: pair, Event: "trade", TradeID: v.TradeID, Type: v.Type, Rate: v.Rate, Amount: v.Amount, Total: v.Total, TS: v.ts})
+	}
+	return nil
+}
+
+// RunChartData drives the strategy with a market's OHLC data.
+// Each candle is emitted as a trade event at its close price and time, and as a <pair>-candle event carrying the ChartDataEntry.
+// The candle period is taken from the gap between the first two candles.
+func (b *Backtest) RunChartData(pair string, chartData ChartData) error {
+	candles := append(ChartData{}, chartData...)
+	sort.SliceStable(candles, func(i, j int) bool { return candles[i].Date < candles[j].Date })
+	period := int64(300)
+	if len(candles) > 1 {
+		period = candles[1].Date - candles[0].Date
+	}
+	for _, v := range candles {
+		ts := time.Unix(v.Date+period, 0)
+		tick := backtestTick{pair: pair, ts: ts, open: v.Open, high: v.High, low: v.Low, close: v.Close, volume: v.QuoteVolume}
+		b.step(tick)
+		typ := "sell"
+		if v.Close >= v.Open {
+			typ = "buy"
+		}
+		b.emit(WSOrderbook{Pair: pair, Event: "trade", Type: typ, Rate: v.Close, Amount: v.QuoteVolume, Total: v.Volume, TS: ts})
+		b.emitter.EmitSync(pair+"-candle", v)
+	}
+	return nil
+}
+
+// RunReplay drives the strategy with a websocket recording, book and ticker events are passed through untouched
+// and orders are filled against the recorded trades.
+func (b *Backtest) RunReplay(ctx context.Context, r *Replayer) error {
+	c := r.Client()
+	c.On("trade", func(m WSOrderbook) {
+		b.step(backtestTick{pair: m.Pair, ts: m.TS, open: m.Rate, high: m.Rate, low: m.Rate, close: m.Rate, volume: m.Amount})
+		b.emit(m)
+	})
+	book := func(m WSOrderbook) {
+		b.mutex.Lock()
+		if m.TS.After(b.now) {
+			b.now = m.TS
+		}
+		b.mutex.Unlock()
+		b.emit(m)
+	}
+	c.On("modify", book).On("remove", book)
+	c.On("ticker", func(m WSTicker) {
+		b.emitter.EmitSync("ticker", m)
+	})
+	return c.Replay(ctx, r, ReplayMaxSpeed)
+}
+
+func (b *Backtest) emit(m WSOrderbook) {
+	b.emitter.EmitSync(m.Event, m).EmitSync(m.Pair, m).EmitSync(m.Pair+"-"+m.Event, m)
+}
+
+// step advances the clock to the tick, fills any orders the tick crosses and records the equity
+func (b *Backtest) step(t backtestTick) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.equity) == 0 {
+		b.last[t.pair] = t.open
+		b.start = b.valuation()
+	}
+	b.now = t.ts
+	remaining := b.orders[:0]
+	for _, o := range b.orders {
+		if o.pair != t.pair || o.active.After(t.ts) || !b.match(o, t) {
+			remaining = append(remaining, o)
+		}
+	}
+	b.orders = remaining
+	b.last[t.pair] = t.close
+	b.equity = append(b.equity, EquityPoint{TS: t.ts, Equity: b.valuation()})
+}
+
+// match fills the order if the tick crosses it, orders which cross on arrival are taker fills
+func (b *Backtest) match(o *backtestOrder, t backtestTick) bool {
+	first := !o.checked
+	o.checked = true
+	buy := o.Type == "buy"
+	switch {
+	case first && buy && t.open <= o.Rate:
+		b.fill(o, math.Min(o.Rate, t.open*(1+b.config.Slippage)), false)
+	case first && !buy && t.open >= o.Rate:
+		b.fill(o, math.Max(o.Rate, t.open*(1-b.config.Slippage)), false)
+	case buy && t.low < o.Rate:
+		b.fill(o, o.Rate, true)
+	case !buy && t.high > o.Rate:
+		b.fill(o, o.Rate, true)
+	default:
+		return false
+	}
+	return true
+}
+
+func (b *Backtest) fill(o *backtestOrder, rate float64, maker bool) {
+	base, quote := splitPair(o.pair)
+	feeRate := b.config.Fees.TakerFee
+	if maker {
+		feeRate = b.config.Fees.MakerFee
+	}
+	total := rate * o.Amount
+	trade := BacktestTrade{
+		TS:          b.now,
+		OrderNumber: o.OrderNumber,
+		TradeID:     b.nextTradeID + 1,
+		Pair:        o.pair,
+		Type:        o.Type,
+		Rate:        rate,
+		Amount:      o.Amount,
+		Total:       total,
+		Maker:       maker,
+	}
+	b.nextTradeID++
+	b.release(o)
+	if o.Type == "buy" {
+		trade.Fee, trade.FeeCurrency = o.Amount*feeRate, quote
+		b.balances[base] -= total
+		b.balances[quote] += o.Amount - trade.Fee
+	} else {
+		trade.Fee, trade.FeeCurrency = total*feeRate, base
+		b.balances[quote] -= o.Amount
+		b.balances[base] += total - trade.Fee
+	}
+	b.turnover += b.value(base, total)
+	b.trades = append(b.trades, trade)
+}
+
+// release returns the funds reserved for an order to the available balance
+func (b *Backtest) release(o *backtestOrder) {
+	base, quote := splitPair(o.pair)
+	currency, reserved := base, o.Total
+	if o.Type == "sell" {
+		currency, reserved = quote, o.Amount
+	}
+	b.onOrders[currency] -= reserved
+	b.balances[currency] += reserved
+}
+
+// value converts an amount into the report currency using the last seen prices, unknown currencies are valued at zero
+func (b *Backtest) value(currency string, amount float64) float64 {
+	c := b.config.Currency
+	if currency == c {
+		return amount
+	}
+	if rate, ok := b.last[c+"_"+currency]; ok {
+		return amount * rate
+	}
+	if rate, ok := b.last[currency+"_"+c]; ok && rate != 0 {
+		return amount / rate
+	}
+	return 0
+}
+
+func (b *Backtest) valuation() (equity float64) {
+	for k, v := range b.balances {
+		equity += b.value(k, v+b.onOrders[k])
+	}
+	for k, v := range b.onOrders {
+		if _, ok := b.balances[k]; !ok {
+			equity += b.value(k, v)
+		}
+	}
+	return
+}
+
+// Report summarises the backtest so far
+func (b *Backtest) Report() (report BacktestReport) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	report.Equity = append([]EquityPoint{}, b.equity...)
+	report.Trades = append([]BacktestTrade{}, b.trades...)
+	report.Balances = map[string]float64{}
+	for k, v := range b.balances {
+		report.Balances[k] = v + b.onOrders[k]
+	}
+	if len(b.equity) == 0 {
+		return
+	}
+	report.Start = b.equity[0].TS
+	report.End = b.equity[len(b.equity)-1].TS
+	report.StartEquity = b.start
+	report.EndEquity = b.equity[len(b.equity)-1].Equity
+	if b.start != 0 {
+		report.Return = report.EndEquity/b.start - 1
+		report.Turnover = b.turnover / b.start
+	}
+	report.MaxDrawdown = maxDrawdown(b.equity)
+	report.Sharpe = sharpe(b.equity)
+	return
+}
+
+// String formats the headline figures of the report
+func (r BacktestReport) String() string {
+	return fmt.Sprintf("%s - %s equity %.8f -> %.8f return %.2f%% max drawdown %.2f%% sharpe %.2f turnover %.2f trades %d",
+		r.Start.UTC().Format(timeLayout), r.End.UTC().Format(timeLayout), r.StartEquity, r.EndEquity,
+		r.Return*100, r.MaxDrawdown*100, r.Sharpe, r.Turnover, len(r.Trades))
+}
+
+// maxDrawdown is the largest peak to trough fall of the equity curve, as a fraction of the peak
+func maxDrawdown(equity []EquityPoint) (dd float64) {
+	peak := 0.0
+	for _, v := range equity {
+		if v.Equity > peak {
+			peak = v.Equity
+		}
+		if peak > 0 {
+			dd = math.Max(dd, (peak-v.Equity)/peak)
+		}
+	}
+	return
+}
+
+// sharpe is the annualised Sharpe ratio (zero risk free rate) of the returns between equity points
+func sharpe(equity []EquityPoint) float64 {
+	returns := []float64{}
+	for i := 1; i < len(equity); i++ {
+		if equity[i-1].Equity > 0 {
+			returns = append(returns, equity[i].Equity/equity[i-1].Equity-1)
+		}
+	}
+	span := equity[len(equity)-1].TS.Sub(equity[0].TS)
+	if len(returns) < 2 || span <= 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	if std == 0 {
+		return 0
+	}
+	periodsPerYear := float64(365*24*time.Hour) / (float64(span) / float64(len(returns)))
+	return mean / std * math.Sqrt(periodsPerYear)
+}
@@ -0,0 +1,43 @@
+package poloniex
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleBacktest() {
+	cd := ChartData{
+		{Date: 1500000000, Open: 100, High: 105, Low: 95, Close: 100},
+		{Date: 1500000300, Open: 100, High: 101, Low: 89, Close: 90},
+		{Date: 1500000600, Open: 90, High: 112, Low: 90, Close: 110},
+	}
+	bt := NewBacktest(BacktestConfig{
+		Balances: map[string]float64{"USDT": 1000},
+		Fees:     FeeInfo{MakerFee: 0.001, TakerFee: 0.002},
+		Currency: "USDT",
+	})
+
+	// buy the dip, the same handler works against a live client
+	var strategy = func(t Trader) {
+		t.On("USDT_BTC-trade", func(m WSOrderbook) {
+			if orders, _ := t.OpenOrders("USDT_BTC"); len(orders) == 0 && m.Rate == 100 {
+				if _, err := t.Buy("USDT_BTC", 91, 5); err != nil {
+					log.Println(err)
+				}
+			}
+		})
+	}
+	strategy(bt)
+
+	if err := bt.RunChartData("USDT_BTC", cd); err != nil {
+		log.Fatalln(err)
+	}
+	report := bt.Report()
+	for _, t := range report.Trades {
+		fmt.Println(t.Type, t.Rate, t.Amount, t.Fee, t.FeeCurrency, t.Maker)
+	}
+	fmt.Printf("%.4f %.4f\n", report.EndEquity, report.MaxDrawdown)
+	// Output:
+	// buy 91 5 0.005 BTC true
+	// 1094.4500 0.0055
+}
@@ -0,0 +1,137 @@
+package poloniex
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CancelStatus is the outcome of cancelling a single order
+type CancelStatus int
+
+const (
+	// CancelSucceeded is an order which was cancelled
+	CancelSucceeded CancelStatus = iota
+	// CancelAlreadyDone is an order which had already filled or been cancelled by the time it was cancelled
+	CancelAlreadyDone
+	// CancelFailed is an order which could not be cancelled, and may still be on the book
+	CancelFailed
+)
+
+// cancelWorkers is how many cancels are sent at once, the client's rate limiter spaces them out
+const cancelWorkers = 4
+
+type (
+	// CancelResult is the outcome of cancelling one open order
+	CancelResult struct {
+		Pair   string
+		Order  OpenOrder
+		Status CancelStatus
+		Err    error
+	}
+
+	// CancelReport is the outcome of a bulk cancel, with a result per order
+	CancelReport struct {
+		Results     []CancelResult
+		Cancelled   int
+		AlreadyDone int
+		Failed      int
+	}
+
+	// CancelFilter selects the open orders CancelWhere cancels
+	CancelFilter func(pair string, order OpenOrder) bool
+)
+
+// String returns the name of the status
+func (s CancelStatus) String() string {
+	switch s {
+	case CancelSucceeded:
+		return "cancelled"
+	case CancelAlreadyDone:
+		return "already-done"
+	}
+	return "failed"
+}
+
+// CancelAll cancels every open order in every market
+func (p *Poloniex) CancelAll(ctx context.Context) (CancelReport, error) {
+	return p.CancelWhere(ctx, func(string, OpenOrder) bool { return true })
+}
+
+// CancelAllForPair cancels every open order in a market
+func (p *Poloniex) CancelAllForPair(ctx context.Context, pair string) (CancelReport, error) {
+	return p.CancelWhere(ctx, func(pr string, _ OpenOrder) bool { return pr == pair })
+}
+
+// CancelWhere fetches the open orders and cancels those selected by filter concurrently, within the client's rate limit.
+// The error is only for failing to fetch the open orders, the outcome of each cancel is in the report.
+// Orders not yet cancelled when ctx is done, including any waiting on the rate limiter, are reported as failed.
+func (p *Poloniex) CancelWhere(ctx context.Context, filter CancelFilter) (report CancelReport, err error) {
+	open, err := p.openOrdersAll(ctx)
+	if err != nil {
+		return report, errors.Wrap(err, "fetching open orders failed")
+	}
+	for pair, orders := range open {
+		for _, o := range orders {
+			if filter(pair, o) {
+				report.Results = append(report.Results, CancelResult{Pair: pair, Order: o})
+			}
+		}
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		return a.Pair < b.Pair || (a.Pair == b.Pair && a.Order.OrderNumber < b.Order.OrderNumber)
+	})
+
+	jobs := make(chan *CancelResult)
+	wg := sync.WaitGroup{}
+	for i := 0; i < cancelWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				p.cancelOne(ctx, r)
+			}
+		}()
+	}
+	for i := range report.Results {
+		jobs <- &report.Results[i]
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range report.Results {
+		switch r.Status {
+		case CancelSucceeded:
+			report.Cancelled++
+		case CancelAlreadyDone:
+			report.AlreadyDone++
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// cancelOne cancels the order of a result, filling in its outcome
+func (p *Poloniex) cancelOne(ctx context.Context, r *CancelResult) {
+	if err := ctx.Err(); err != nil {
+		r.Status, r.Err = CancelFailed, err
+		return
+	}
+	success, err := p.cancelOrder(ctx, r.Order.OrderNumber)
+	switch {
+	case err != nil && strings.Contains(err.Error(), "Invalid order number"):
+		// the exchange gives the same error for an order which has filled or already been cancelled
+		r.Status = CancelAlreadyDone
+	case err != nil:
+		r.Status, r.Err = CancelFailed, err
+	case !success:
+		r.Status, r.Err = CancelFailed, errors.New("cancel was not successful")
+	default:
+		r.Status = CancelSucceeded
+	}
+}
@@ -0,0 +1,70 @@
+package poloniex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func ExamplePoloniex_CancelWhere() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			switch {
+			case req.Command == "cancelOrder" && req.Params.Get("orderNumber") == "2":
+				// already filled or cancelled orders are rejected with this error
+				return nil, errors.New("Invalid order number, or you are not the person who placed the order.")
+			case req.Command == "cancelOrder" && req.Params.Get("orderNumber") == "3":
+				return &Response{Status: 200, Body: `{"success":0}`}, nil
+			}
+			return next(req)
+		}
+	}, answer(map[string]string{
+		"returnOpenOrders": `{
+			"USDT_BTC":[{"orderNumber":"1","type":"buy","rate":"9000","amount":"1"},{"orderNumber":"2","type":"buy","rate":"8000","amount":"1"}],
+			"USDT_ETH":[{"orderNumber":"3","type":"sell","rate":"300","amount":"1"},{"orderNumber":"4","type":"sell","rate":"310","amount":"1"}]}`,
+		"cancelOrder": `{"success":1}`,
+	}))
+	report, err := p.CancelWhere(context.Background(), func(pair string, o OpenOrder) bool { return o.OrderNumber < 4 })
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, r := range report.Results {
+		fmt.Println(r.Pair, r.Order.OrderNumber, r.Status, r.Err)
+	}
+	fmt.Println(report.Cancelled, report.AlreadyDone, report.Failed)
+
+	// cancels still waiting on the rate limiter when ctx is done give up
+	q := NewREST("key", "secret")
+	q.SetRateLimit(0.01, 1)
+	q.DryRun(ioutil.Discard)
+	q.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			if req.Command == "returnOpenOrders" {
+				return &Response{Status: 200, Body: `{"USDT_BTC":[{"orderNumber":"1"},{"orderNumber":"2"},{"orderNumber":"3"}]}`}, nil
+			}
+			return next(req)
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	report, _ = q.CancelWhere(ctx, func(string, OpenOrder) bool { return true })
+	waiting := 0
+	for _, r := range report.Results {
+		if errors.Cause(r.Err) == context.DeadlineExceeded {
+			waiting++
+		}
+	}
+	fmt.Println(report.Failed, waiting)
+	// Output:
+	// USDT_BTC 1 cancelled <nil>
+	// USDT_BTC 2 already-done <nil>
+	// USDT_ETH 3 failed cancel was not successful
+	// 1 1 1
+	// 3 2
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	poloniex "github.com/pharrisee/poloniex-api"
+	"github.com/pkg/errors"
+)
+
+const (
+	// tapeLength is how many trades are kept per pair
+	tapeLength = 100
+	// frameRate is how often the screen is redrawn
+	frameRate = 250 * time.Millisecond
+)
+
+type (
+	// dashboard holds everything shown on screen, filled in by websocket events and polling
+	dashboard struct {
+		p       *poloniex.Poloniex
+		private bool
+		pairs   []string
+		books   map[string]*poloniex.LiveBook
+
+		mutex     sync.Mutex
+		selected  int
+		cursor    int
+		confirm   bool
+		tickers   map[string]poloniex.WSTicker
+		tapes     map[string][]poloniex.WSOrderbook
+		counts    map[string]int
+		lastEvent time.Time
+		orders    poloniex.OpenOrdersAll
+		balances  poloniex.Balances
+		refreshed time.Time
+		status    string
+		refresh   chan struct{}
+	}
+)
+
+// newDashboard subscribes to the pairs and the ticker, and seeds a live book for each pair
+func newDashboard(p *poloniex.Poloniex, pairs []string, private bool) (*dashboard, error) {
+	d := &dashboard{
+		p:       p,
+		private: private,
+		pairs:   pairs,
+		books:   map[string]*poloniex.LiveBook{},
+		tickers: map[string]poloniex.WSTicker{},
+		tapes:   map[string][]poloniex.WSOrderbook{},
+		counts:  map[string]int{},
+		refresh: make(chan struct{}, 1),
+	}
+	p.On("ticker", d.ticker)
+	for _, pair := range pairs {
+		if _, ok := p.ByName[pair]; !ok {
+			return nil, errors.New("unknown pair " + pair)
+		}
+		p.On(pair, d.event).On(pair+"-trade", d.trade)
+		if err := p.Subscribe(pair); err != nil {
+			return nil, errors.Wrap(err, "subscribing to "+pair+" failed")
+		}
+		b, err := p.LiveBook(pair)
+		if err != nil {
+			return nil, err
+		}
+		d.books[pair] = b
+	}
+	if err := p.Subscribe("ticker"); err != nil {
+		return nil, errors.Wrap(err, "subscribing to the ticker failed")
+	}
+	return d, nil
+}
+
+func (d *dashboard) ticker(t poloniex.WSTicker) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, pair := range d.pairs {
+		if pair == t.Pair {
+			d.tickers[pair] = t
+			d.counts["ticker"]++
+			d.lastEvent = time.Now()
+		}
+	}
+}
+
+func (d *dashboard) event(m poloniex.WSOrderbook) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.counts[m.Pair]++
+	d.lastEvent = time.Now()
+}
+
+func (d *dashboard) trade(m poloniex.WSOrderbook) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	tape := append([]poloniex.WSOrderbook{m}, d.tapes[m.Pair]...)
+	if len(tape) > tapeLength {
+		tape = tape[:tapeLength]
+	}
+	d.tapes[m.Pair] = tape
+}
+
+// poll fetches the open orders and balances every interval, or sooner when asked to refresh
+func (d *dashboard) poll(interval time.Duration) {
+	if !d.private {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.fetch()
+		select {
+		case <-ticker.C:
+		case <-d.refresh:
+		}
+	}
+}
+
+func (d *dashboard) fetch() {
+	orders, err := d.p.OpenOrdersAll()
+	if err != nil {
+		d.setStatus("fetching open orders failed: %s", err)
+		return
+	}
+	balances, err := d.p.Balances()
+	if err != nil {
+		d.setStatus("fetching balances failed: %s", err)
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.orders = orders
+	d.balances = balances
+	d.refreshed = time.Now()
+	d.clampCursor()
+}
+
+// requestRefresh asks poll to fetch now, without waiting if a refresh is already pending
+func (d *dashboard) requestRefresh() {
+	select {
+	case d.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dashboard) setStatus(format string, args ...interface{}) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.status = time.Now().Format("15:04:05 ") + fmt.Sprintf(format, args...)
+}
+
+// pair returns the selected pair, the mutex must be held
+func (d *dashboard) pair() string {
+	return d.pairs[d.selected]
+}
+
+// clampCursor keeps the cursor on one of the selected pair's orders, the mutex must be held
+func (d *dashboard) clampCursor() {
+	n := len(d.orders[d.pair()])
+	if d.cursor >= n {
+		d.cursor = n - 1
+	}
+	if d.cursor < 0 {
+		d.cursor = 0
+	}
+}
+
+// run draws the dashboard and handles keys until q is pressed
+func (d *dashboard) run() error {
+	term, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer term.restore()
+	// alternate screen with the cursor hidden, put back on the way out
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan key)
+	go readKeys(os.Stdin, keys)
+	frame := time.NewTicker(frameRate)
+	defer frame.Stop()
+	for {
+		width, height := term.size()
+		fmt.Print(d.render(width, height))
+		select {
+		case k, ok := <-keys:
+			if !ok || !d.handle(k) {
+				return nil
+			}
+		case <-frame.C:
+		}
+	}
+}
+
+// handle acts on a key, returning false to quit
+func (d *dashboard) handle(k key) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.confirm {
+		d.confirm = false
+		if k == key('y') {
+			go d.cancelAll(d.pair())
+		} else {
+			d.status = "cancel all aborted"
+		}
+		return true
+	}
+	switch k {
+	case key('q'), keyCtrlC:
+		return false
+	case keyTab, keyRight:
+		d.selected = (d.selected + 1) % len(d.pairs)
+		d.clampCursor()
+	case keyBackTab, keyLeft:
+		d.selected = (d.selected + len(d.pairs) - 1) % len(d.pairs)
+		d.clampCursor()
+	case keyUp, key('k'):
+		d.cursor--
+		d.clampCursor()
+	case keyDown, key('j'):
+		d.cursor++
+		d.clampCursor()
+	case key('r'):
+		d.requestRefresh()
+	case key('c'):
+		orders := d.orders[d.pair()]
+		if d.cursor < len(orders) {
+			go d.cancel(orders[d.cursor])
+		}
+	case key('C'):
+		if len(d.orders[d.pair()]) > 0 {
+			d.confirm = true
+			d.status = fmt.Sprintf("cancel all %d orders in %s? press y to confirm", len(d.orders[d.pair()]), d.pair())
+		}
+	}
+	return true
+}
+
+func (d *dashboard) cancel(o poloniex.OpenOrder) {
+	d.setStatus("cancelling %d", o.OrderNumber)
+	if _, err := d.p.CancelOrder(o.OrderNumber); err != nil {
+		d.setStatus("cancelling %d failed: %s", o.OrderNumber, err)
+		return
+	}
+	d.setStatus("cancelled %d", o.OrderNumber)
+	d.requestRefresh()
+}
+
+func (d *dashboard) cancelAll(pair string) {
+	d.setStatus("cancelling all orders in %s", pair)
+	report, err := d.p.CancelAllForPair(context.Background(), pair)
+	if err != nil {
+		d.setStatus("cancelling all orders in %s failed: %s", pair, err)
+		return
+	}
+	d.setStatus("%s: %d cancelled, %d already done, %d failed", pair, report.Cancelled, report.AlreadyDone, report.Failed)
+	d.requestRefresh()
+}
+
+// check waits for events for the given time, then writes how many each pair received,
+// reporting whether every pair and the ticker received at least one
+func (d *dashboard) check(w io.Writer, wait time.Duration) bool {
+	time.Sleep(wait)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	names := append([]string{"ticker"}, d.pairs...)
+	ok := true
+	for _, name := range names {
+		n := d.counts[name]
+		result := "ok"
+		if n == 0 {
+			result, ok = "FAIL", false
+		}
+		fmt.Fprintf(w, "%-12s %6d events  %s\n", name, n, result)
+	}
+	for _, pair := range d.pairs {
+		if bid, ask, live := d.books[pair].Best(); live {
+			fmt.Fprintf(w, "%-12s book %s / %s, %d trades\n", pair, number(bid), number(ask), len(d.tapes[pair]))
+		}
+	}
+	if d.private {
+		n := 0
+		for _, orders := range d.orders {
+			n += len(orders)
+		}
+		fmt.Fprintf(w, "%d open orders, %d balances\n", n, len(d.balances))
+	}
+	return ok
+}
+
+// sortedBalances returns the currencies holding anything, largest BTC value first, the mutex must be held
+func (d *dashboard) sortedBalances() []string {
+	currencies := []string{}
+	for currency, b := range d.balances {
+		if b.Available > 0 || b.OnOrders > 0 {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		bi, bj := d.balances[currencies[i]].BTCValue, d.balances[currencies[j]].BTCValue
+		if bi != bj {
+			return bi > bj
+		}
+		return currencies[i] < currencies[j]
+	})
+	return currencies
+}
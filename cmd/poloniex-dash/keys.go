@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// key is a key press, printable keys are their rune and special keys are negative
+type key rune
+
+const (
+	keyUp key = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyBackTab
+
+	keyTab   key = '\t'
+	keyCtrlC key = 3
+)
+
+// arrows maps the final byte of an escape sequence to its key
+var arrows = map[byte]key{'A': keyUp, 'B': keyDown, 'C': keyRight, 'D': keyLeft, 'Z': keyBackTab}
+
+// readKeys decodes key presses from a raw terminal until it is closed
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+	in := bufio.NewReader(r)
+	for {
+		c, _, err := in.ReadRune()
+		if err != nil {
+			return
+		}
+		if c != 0x1b {
+			keys <- key(c)
+			continue
+		}
+		// escape sequences are ESC [ or ESC O followed by the final byte
+		if in.Buffered() < 2 {
+			continue
+		}
+		if b, _ := in.ReadByte(); b != '[' && b != 'O' {
+			continue
+		}
+		b, _ := in.ReadByte()
+		if k, ok := arrows[b]; ok {
+			keys <- k
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func Example_readKeys() {
+	names := map[key]string{keyUp: "up", keyDown: "down", keyRight: "right", keyLeft: "left", keyBackTab: "backtab",
+		keyTab: "tab", keyCtrlC: "ctrl-c"}
+	keys := make(chan key)
+	// arrows come in both cursor key modes, an escape cut short at the end is dropped
+	go readKeys(strings.NewReader("j\x1b[A\x1bOB\x1b[C\x1b[D\t\x1b[Z\x03q\x1b"), keys)
+	for k := range keys {
+		if name, ok := names[k]; ok {
+			fmt.Println(name)
+			continue
+		}
+		fmt.Println(string(k))
+	}
+	// Output:
+	// j
+	// up
+	// down
+	// right
+	// left
+	// tab
+	// backtab
+	// ctrl-c
+	// q
+}
@@ -0,0 +1,102 @@
+// Command poloniex-dash is a terminal dashboard showing a live order book ladder, trades tape and
+// ticker for a set of pairs, along with your open orders and balances.
+//
+// Usage:
+//
+//	poloniex-dash [flags] [pair ...]
+//
+// The pairs default to USDT_BTC. Credentials are taken from -key and -secret, a -config file, or the
+// POLONIEX_KEY and POLONIEX_SECRET environment variables; without them only the market data is shown.
+//
+// Keys:
+//
+//	tab, right, left  switch pair
+//	up, down          select an open order
+//	c                 cancel the selected order
+//	C                 cancel every order in the pair, after confirming with y
+//	r                 refresh orders and balances
+//	q                 quit
+//
+// With -check the dashboard runs without a terminal for the given time, then reports the events received
+// per pair and exits non zero if any pair received none, as a smoke test of the streaming api.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	poloniex "github.com/pharrisee/poloniex-api"
+)
+
+func main() {
+	flags := flag.NewFlagSet("poloniex-dash", flag.ExitOnError)
+	key := flags.String("key", "", "API key")
+	secret := flags.String("secret", "", "API secret")
+	config := flags.String("config", "", "JSON file holding the key and secret")
+	refresh := flags.Duration("refresh", 5*time.Second, "how often to poll open orders and balances")
+	check := flags.Duration("check", 0, "run without a terminal for this long and report the events received")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: poloniex-dash [flags] [pair ...]\n\nflags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	pairs := flags.Args()
+	if len(pairs) == 0 {
+		pairs = []string{"USDT_BTC"}
+	}
+	for i := range pairs {
+		pairs[i] = strings.ToUpper(pairs[i])
+	}
+
+	c := poloniex.Credentials{Key: *key, Secret: *secret}
+	if c.Key == "" || c.Secret == "" {
+		c = credentials(*config)
+	}
+	var p *poloniex.Poloniex
+	if c.Key != "" && c.Secret != "" {
+		p = poloniex.NewWithCredentials(c.Key, c.Secret)
+	} else {
+		p = poloniex.NewPublicOnly()
+	}
+
+	d, err := newDashboard(p, pairs, c.Key != "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go p.StartWS()
+	go d.poll(*refresh)
+
+	if *check > 0 {
+		if !d.check(os.Stdout, *check) {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := d.run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// credentials reads the credentials from the config file if given, otherwise from the environment,
+// returning empty credentials if there are none
+func credentials(config string) poloniex.Credentials {
+	var provider poloniex.CredentialProvider = poloniex.EnvCredentials{}
+	if config != "" {
+		provider = poloniex.FileCredentials{Filename: config}
+	}
+	c, err := provider.Credentials()
+	if err != nil {
+		if config != "" {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return poloniex.Credentials{}
+	}
+	return c
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	poloniex "github.com/pharrisee/poloniex-api"
+)
+
+const (
+	bold    = "\x1b[1m"
+	reverse = "\x1b[7m"
+	red     = "\x1b[31m"
+	green   = "\x1b[32m"
+	dim     = "\x1b[2m"
+	reset   = "\x1b[0m"
+
+	// maxOrders is how many open orders are shown at once, the list scrolls with the cursor
+	maxOrders = 6
+	help      = "tab/arrows pair  up/down order  c cancel  C cancel all  r refresh  q quit"
+)
+
+type (
+	// screen collects the lines of a frame, clipped to the terminal
+	screen struct {
+		width int
+		lines []string
+	}
+
+	// row is a line of one column with its style
+	row struct {
+		style string
+		text  string
+	}
+)
+
+// add adds a line of plain text in a style
+func (s *screen) add(style, text string) {
+	s.lines = append(s.lines, paint(style, fit(text, s.width)))
+}
+
+// split adds a line made of two columns, each in its own style
+func (s *screen) split(leftStyle, left, rightStyle, right string) {
+	half := s.width / 2
+	s.lines = append(s.lines, paint(leftStyle, fit(left, half))+paint(rightStyle, fit(right, s.width-half)))
+}
+
+// render draws the whole dashboard, returning the escape codes and text to print
+func (d *dashboard) render(width, height int) string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	s := &screen{width: width}
+	pair := d.pair()
+
+	// title, pairs and the health of the stream
+	tabs := make([]string, len(d.pairs))
+	for i, p := range d.pairs {
+		tabs[i] = " " + p + " "
+		if i == d.selected {
+			tabs[i] = "[" + p + "]"
+		}
+	}
+	events := 0
+	for _, n := range d.counts {
+		events += n
+	}
+	stream := "waiting for events"
+	if !d.lastEvent.IsZero() {
+		stream = fmt.Sprintf("%d events, last %s ago", events, ago(d.lastEvent))
+	}
+	s.add(bold, fmt.Sprintf("poloniex-dash  %s  %s  %s", strings.Join(tabs, " "), stream, time.Now().Format("15:04:05")))
+	s.add("", "")
+
+	// ticker
+	s.add(bold, fmt.Sprintf("%-12s %14s %14s %14s %8s %14s %14s %14s", "PAIR", "LAST", "BID", "ASK", "CHANGE", "VOLUME", "HIGH", "LOW"))
+	for _, p := range d.pairs {
+		t, ok := d.tickers[p]
+		if !ok {
+			s.add(dim, fmt.Sprintf("%-12s waiting for ticker", p))
+			continue
+		}
+		style := green
+		if t.PercentChange < 0 {
+			style = red
+		}
+		s.add(style, fmt.Sprintf("%-12s %14.8f %14.8f %14.8f %7.2f%% %14.2f %14.8f %14.8f",
+			p, t.Last, t.Bid, t.Ask, t.PercentChange*100, t.BaseVolume, t.DailyHigh, t.DailyLow))
+	}
+	s.add("", "")
+
+	// the rest of the screen below the book is fixed, the book gets what is left
+	below := 4
+	if d.private {
+		below += 2 + maxOrders
+	}
+	rows := height - len(s.lines) - 2 - below
+	if rows < 3 {
+		rows = 3
+	}
+	depth := (rows - 1) / 2
+
+	s.split(bold, "ORDER BOOK "+pair, bold, "TRADES")
+	s.split(bold, fmt.Sprintf("%16s %16s", "RATE", "AMOUNT"), bold, fmt.Sprintf("%-9s %-5s %16s %16s", "TIME", "SIDE", "RATE", "AMOUNT"))
+	book := d.books[pair]
+	asks, bids := book.Asks(depth), book.Bids(depth)
+	tape := d.tapes[pair]
+	ladder := make([]row, 0, 2*depth+1)
+	for i := depth - 1; i >= 0; i-- {
+		if i < len(asks) {
+			ladder = append(ladder, row{red, level(asks[i])})
+		} else {
+			ladder = append(ladder, row{"", ""})
+		}
+	}
+	spread := ""
+	if bid, ask, ok := book.Best(); ok {
+		spread = fmt.Sprintf("%16s %16.8f", "spread", ask-bid)
+	}
+	ladder = append(ladder, row{dim, spread})
+	for i := 0; i < depth; i++ {
+		if i < len(bids) {
+			ladder = append(ladder, row{green, level(bids[i])})
+		} else {
+			ladder = append(ladder, row{"", ""})
+		}
+	}
+	for i, l := range ladder {
+		tradeStyle, trade := "", ""
+		if i < len(tape) {
+			t := tape[i]
+			tradeStyle = red
+			if t.Type == "buy" {
+				tradeStyle = green
+			}
+			trade = fmt.Sprintf("%-9s %-5s %16.8f %16.8f", t.TS.Format("15:04:05"), t.Type, t.Rate, t.Amount)
+		}
+		s.split(l.style, l.text, tradeStyle, trade)
+	}
+	s.add("", "")
+
+	// open orders and balances
+	if d.private {
+		refreshed := "not yet fetched"
+		if !d.refreshed.IsZero() {
+			refreshed = "refreshed " + ago(d.refreshed) + " ago"
+		}
+		orders := d.orders[pair]
+		s.add(bold, fmt.Sprintf("OPEN ORDERS %s (%d, %s)", pair, len(orders), refreshed))
+		first := 0
+		if d.cursor >= maxOrders {
+			first = d.cursor - maxOrders + 1
+		}
+		for i := first; i < first+maxOrders; i++ {
+			if i >= len(orders) {
+				s.add("", "")
+				continue
+			}
+			o := orders[i]
+			style, marker := green, "  "
+			if o.Type == "sell" {
+				style = red
+			}
+			if i == d.cursor {
+				style, marker = reverse, "> "
+			}
+			s.add(style, fmt.Sprintf("%s%-14d %-5s %16.8f %16.8f %16.8f  %s", marker, o.OrderNumber, o.Type, o.Rate, o.Amount, o.Total, o.Date))
+		}
+		balances := []string{}
+		for _, currency := range d.sortedBalances() {
+			b := d.balances[currency]
+			text := currency + " " + number(b.Available)
+			if b.OnOrders > 0 {
+				text += " (+" + number(b.OnOrders) + " on orders)"
+			}
+			balances = append(balances, text)
+		}
+		s.add("", "BALANCES  "+strings.Join(balances, "  "))
+	}
+
+	// pad so the status and help stay on the bottom lines
+	for len(s.lines) < height-2 {
+		s.add("", "")
+	}
+	s.add(bold, d.status)
+	s.add(dim, help)
+	if len(s.lines) > height {
+		s.lines = s.lines[:height]
+	}
+	return "\x1b[H" + strings.Join(s.lines, "\x1b[K\r\n") + "\x1b[K\x1b[J"
+}
+
+func level(o poloniex.Order) string {
+	return fmt.Sprintf("%16.8f %16.8f", o.Rate, o.Amount)
+}
+
+// fit pads or cuts text to exactly width runes
+func fit(text string, width int) string {
+	r := []rune(text)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return text + strings.Repeat(" ", width-len(r))
+}
+
+func paint(style, text string) string {
+	if style == "" {
+		return text
+	}
+	return style + text + reset
+}
+
+func ago(t time.Time) string {
+	return time.Since(t).Truncate(100 * time.Millisecond).String()
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package main
+
+import "github.com/pkg/errors"
+
+type terminal struct{}
+
+func rawTerminal() (*terminal, error) {
+	return nil, errors.New("the dashboard needs a unix terminal")
+}
+
+func (t *terminal) restore() {}
+
+func (t *terminal) size() (width, height int) {
+	return 80, 24
+}
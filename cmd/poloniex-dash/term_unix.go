@@ -0,0 +1,51 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package main
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// terminal is the controlling terminal switched to raw mode, restore puts it back
+type terminal struct {
+	fd       int
+	original unix.Termios
+}
+
+// rawTerminal switches stdin to raw mode, so keys arrive as they are pressed and are not echoed
+func rawTerminal() (*terminal, error) {
+	fd := int(os.Stdin.Fd())
+	t, err := unix.IoctlGetTermios(fd, getTermios)
+	if err != nil {
+		return nil, errors.Wrap(err, "stdin is not a terminal")
+	}
+	term := &terminal{fd: fd, original: *t}
+	raw := *t
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, setTermios, &raw); err != nil {
+		return nil, errors.Wrap(err, "setting raw mode failed")
+	}
+	return term, nil
+}
+
+func (t *terminal) restore() {
+	unix.IoctlSetTermios(t.fd, setTermios, &t.original)
+}
+
+// size returns the width and height of the terminal, falling back to 80x24
+func (t *terminal) size() (width, height int) {
+	ws, err := unix.IoctlGetWinsize(t.fd, unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
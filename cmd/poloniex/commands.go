@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+
+	poloniex "github.com/pharrisee/poloniex-api"
+	"github.com/pkg/errors"
+)
+
+type (
+	// runFunc runs a command with its arguments, returning what should be printed
+	runFunc func(p *poloniex.Poloniex, args []string) (interface{}, error)
+
+	command struct {
+		args    string
+		help    string
+		min     int
+		private bool
+		// setup adds the command's flags and returns the function running it
+		setup func(fs *flag.FlagSet) runFunc
+	}
+
+	// ladderRow is a level of the order book printed by the book command
+	ladderRow struct {
+		BidAmount string
+		Bid       string
+		Ask       string
+		AskAmount string
+	}
+)
+
+// simple is the setup of a command without flags
+func simple(run runFunc) func(*flag.FlagSet) runFunc {
+	return func(*flag.FlagSet) runFunc { return run }
+}
+
+var commands = map[string]command{
+	"ticker": {
+		args: "[pair...]", help: "Last price, best bid and ask and 24 hour volume of every market, or of the pairs given",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			t, err := p.Ticker()
+			if err != nil || len(args) == 0 {
+				return t, err
+			}
+			selected := poloniex.Ticker{}
+			for _, pair := range args {
+				if v, ok := t[strings.ToUpper(pair)]; ok {
+					selected[strings.ToUpper(pair)] = v
+				}
+			}
+			return selected, nil
+		}),
+	},
+	"volume": {
+		help: "24 hour volume of every market",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.DailyVolume()
+		}),
+	},
+	"book": {
+		args: "<pair>", min: 1, help: "Order book of a market as a ladder of bids and asks",
+		setup: func(fs *flag.FlagSet) runFunc {
+			depth := fs.Int("depth", 20, "levels to show")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				ob, err := p.OrderBook(strings.ToUpper(args[0]))
+				if err != nil {
+					return nil, err
+				}
+				rows := []ladderRow{}
+				for i := 0; i < *depth && (i < len(ob.Bids) || i < len(ob.Asks)); i++ {
+					r := ladderRow{}
+					if i < len(ob.Bids) {
+						r.BidAmount, r.Bid = number(ob.Bids[i].Amount), number(ob.Bids[i].Rate)
+					}
+					if i < len(ob.Asks) {
+						r.Ask, r.AskAmount = number(ob.Asks[i].Rate), number(ob.Asks[i].Amount)
+					}
+					rows = append(rows, r)
+				}
+				return rows, nil
+			}
+		},
+	},
+	"trades": {
+		args: "<pair> [start [end]]", min: 1, help: "Recent public trades of a market, or those between start and end",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			dates, err := dates(args[1:])
+			if err != nil {
+				return nil, err
+			}
+			return p.TradeHistory(strings.ToUpper(args[0]), dates...)
+		}),
+	},
+	"candles": {
+		args: "<pair> [start [end]]", min: 1, help: "OHLC candles of a market, for the last day by default",
+		setup: func(fs *flag.FlagSet) runFunc {
+			period := fs.Int("period", 300, "candle period in seconds: 300, 900, 1800, 7200, 14400 or 86400")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				end, start := time.Now(), time.Now().Add(-24*time.Hour)
+				var err error
+				if len(args) > 1 {
+					if start, err = parseTime(args[1]); err != nil {
+						return nil, err
+					}
+				}
+				if len(args) > 2 {
+					if end, err = parseTime(args[2]); err != nil {
+						return nil, err
+					}
+				}
+				return p.ChartDataPeriod(strings.ToUpper(args[0]), start, end, *period)
+			}
+		},
+	},
+	"currencies": {
+		help: "Fees, confirmations and status of every currency",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.Currencies()
+		}),
+	},
+	"loanorders": {
+		args: "<currency>", min: 1, help: "Loan offers for a currency, or demands with -demands",
+		setup: func(fs *flag.FlagSet) runFunc {
+			demands := fs.Bool("demands", false, "show demands rather than offers")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				lo, err := p.LoanOrders(strings.ToUpper(args[0]))
+				if *demands {
+					return lo.Demands, err
+				}
+				return lo.Offers, err
+			}
+		},
+	},
+
+	"balances": {
+		private: true, help: "Exchange balances, leaving out empty ones unless -all is given",
+		setup: func(fs *flag.FlagSet) runFunc {
+			all := fs.Bool("all", false, "include empty balances")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				b, err := p.Balances()
+				if err != nil || *all {
+					return b, err
+				}
+				for k, v := range b {
+					if v.Available == 0 && v.OnOrders == 0 {
+						delete(b, k)
+					}
+				}
+				return b, nil
+			}
+		},
+	},
+	"accounts": {
+		private: true, help: "Balances of the exchange, margin and lending accounts",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.AccountBalances()
+		}),
+	},
+	"available": {
+		private: true, help: "Available balances of the exchange, margin and lending accounts",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.AvailableAccountBalances()
+		}),
+	},
+	"tradable": {
+		private: true, help: "Tradable balances of every margin market",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.TradableBalances()
+		}),
+	},
+	"addresses": {
+		private: true, help: "Deposit addresses",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.Addresses()
+		}),
+	},
+	"newaddress": {
+		args: "<currency>", min: 1, private: true, help: "Generates a new deposit address for a currency",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.GenerateNewAddress(strings.ToUpper(args[0]))
+		}),
+	},
+	"funding": {
+		args: "[start [end]]", private: true, help: "Deposits, withdrawals or adjustments, for the last 6 months by default",
+		setup: func(fs *flag.FlagSet) runFunc {
+			kind := fs.String("type", "deposits", "deposits, withdrawals or adjustments")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				dates, err := dates(args)
+				if err != nil {
+					return nil, err
+				}
+				dw, err := p.DepositsWithdrawals(dates...)
+				switch *kind {
+				case "withdrawals":
+					return dw.Withdrawals, err
+				case "adjustments":
+					return dw.Adjustments, err
+				}
+				return dw.Deposits, err
+			}
+		},
+	},
+	"orders": {
+		args: "[pair]", private: true, help: "Open orders in every market, or in the pair given",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			if len(args) > 0 {
+				return p.OpenOrders(strings.ToUpper(args[0]))
+			}
+			return p.OpenOrdersAll()
+		}),
+	},
+	"history": {
+		args: "[pair [start [end]]]", private: true, help: "Your trades in every market, or in the pair given",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			if len(args) == 0 || strings.ToLower(args[0]) == "all" {
+				dates, err := dates(tail(args))
+				if err != nil {
+					return nil, err
+				}
+				return p.PrivateTradeHistoryAll(dates...)
+			}
+			dates, err := dates(args[1:])
+			if err != nil {
+				return nil, err
+			}
+			return p.PrivateTradeHistory(strings.ToUpper(args[0]), dates...)
+		}),
+	},
+	"ordertrades": {
+		args: "<order number>", min: 1, private: true, help: "Trades of an order",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			n, err := integer(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return p.OrderTrades(n)
+		}),
+	},
+	"orderstatus": {
+		args: "<order number>", min: 1, private: true, help: "Status of an open order",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			n, err := integer(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return p.OrderStatus(n)
+		}),
+	},
+	"buy":  order("buy"),
+	"sell": order("sell"),
+	"marginbuy": {
+		args: "<pair> <rate> <amount>", min: 3, private: true, help: "Places a margin buy order",
+		setup: func(fs *flag.FlagSet) runFunc {
+			lendingRate := fs.Float64("lending-rate", 0.02, "highest lending rate accepted, as a percentage")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				rate, amount, err := rateAmount(args[1], args[2])
+				if err != nil {
+					return nil, err
+				}
+				return p.MarginBuy(strings.ToUpper(args[0]), rate, *lendingRate/100, amount)
+			}
+		},
+	},
+	"marginsell": {
+		args: "<pair> <rate> <amount>", min: 3, private: true, help: "Places a margin sell order",
+		setup: func(fs *flag.FlagSet) runFunc {
+			lendingRate := fs.Float64("lending-rate", 0.02, "highest lending rate accepted, as a percentage")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				rate, amount, err := rateAmount(args[1], args[2])
+				if err != nil {
+					return nil, err
+				}
+				return p.MarginSell(strings.ToUpper(args[0]), rate, *lendingRate/100, amount)
+			}
+		},
+	},
+	"move": {
+		args: "<order number> <rate>", min: 2, private: true, help: "Moves an order to a new rate",
+		setup: func(fs *flag.FlagSet) runFunc {
+			postOnly := fs.Bool("post-only", false, "only move if nothing fills immediately")
+			ioc := fs.Bool("ioc", false, "immediate or cancel")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				n, err := integer(args[0])
+				if err != nil {
+					return nil, err
+				}
+				rate, err := strconv.ParseFloat(args[1], 64)
+				if err != nil {
+					return nil, errors.Wrap(err, "bad rate")
+				}
+				switch {
+				case *postOnly:
+					return p.MovePostOnly(n, rate)
+				case *ioc:
+					return p.MoveImmediateOrCancel(n, rate)
+				}
+				return p.Move(n, rate)
+			}
+		},
+	},
+	"cancel": {
+		args: "<order number...>", min: 1, private: true, help: "Cancels orders",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			type cancelled struct {
+				OrderNumber int64
+				Success     bool
+			}
+			results := []cancelled{}
+			for _, a := range args {
+				n, err := integer(a)
+				if err != nil {
+					return nil, err
+				}
+				ok, err := p.CancelOrder(n)
+				if err != nil {
+					return nil, errors.Wrapf(err, "cancelling %d failed", n)
+				}
+				results = append(results, cancelled{n, ok})
+			}
+			return results, nil
+		}),
+	},
+	"cancelall": {
+		args: "[pair]", private: true, help: "Cancels every open order, or those in the pair given",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			var report poloniex.CancelReport
+			var err error
+			if len(args) > 0 {
+				report, err = p.CancelAllForPair(context.Background(), strings.ToUpper(args[0]))
+			} else {
+				report, err = p.CancelAll(context.Background())
+			}
+			type result struct {
+				Pair        string
+				OrderNumber int64
+				Status      string
+				Error       string
+			}
+			results := []result{}
+			for _, r := range report.Results {
+				e := ""
+				if r.Err != nil {
+					e = r.Err.Error()
+				}
+				results = append(results, result{r.Pair, r.Order.OrderNumber, r.Status.String(), e})
+			}
+			return results, err
+		}),
+	},
+	"withdraw": {
+		args: "<currency> <amount> <address>", min: 3, private: true, help: "Withdraws to an address, with no email confirmation",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			amount, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "bad amount")
+			}
+			return p.Withdraw(strings.ToUpper(args[0]), amount, args[2])
+		}),
+	},
+	"fees": {
+		private: true, help: "Your trading fees and 30 day volume",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.FeeInfo()
+		}),
+	},
+	"transfer": {
+		args: "<currency> <amount> <from> <to>", min: 4, private: true,
+		help: "Transfers between the exchange, margin and lending accounts",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			amount, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "bad amount")
+			}
+			return p.TransferBalance(strings.ToUpper(args[0]), amount, args[2], args[3])
+		}),
+	},
+	"margin": {
+		private: true, help: "Summary of the margin account",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.MarginAccountSummary()
+		}),
+	},
+	"positions": {
+		args: "[pair]", private: true, help: "Margin positions in every market, or in the pair given",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			if len(args) > 0 {
+				return p.MarginPosition(strings.ToUpper(args[0]))
+			}
+			return p.MarginPositionAll()
+		}),
+	},
+	"close": {
+		args: "<pair>", min: 1, private: true, help: "Closes a margin position at market",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.CloseMarginPosition(strings.ToUpper(args[0]))
+		}),
+	},
+	"lend": {
+		args: "<currency> <amount> <days> <rate%>", min: 4, private: true, help: "Offers a loan, the rate is a daily percentage",
+		setup: func(fs *flag.FlagSet) runFunc {
+			renew := fs.Bool("renew", false, "renew the loan automatically")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				amount, err := strconv.ParseFloat(args[1], 64)
+				if err != nil {
+					return nil, errors.Wrap(err, "bad amount")
+				}
+				days, err := strconv.Atoi(args[2])
+				if err != nil {
+					return nil, errors.Wrap(err, "bad duration")
+				}
+				rate, err := strconv.ParseFloat(strings.TrimSuffix(args[3], "%"), 64)
+				if err != nil {
+					return nil, errors.Wrap(err, "bad rate")
+				}
+				return p.LoanOffer(strings.ToUpper(args[0]), amount, days, *renew, rate)
+			}
+		},
+	},
+	"cancelloan": {
+		args: "<offer number>", min: 1, private: true, help: "Cancels a loan offer",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			n, err := integer(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return p.CancelLoanOffer(n)
+		}),
+	},
+	"loans": {
+		private: true, help: "Your open loan offers",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.OpenLoanOffers()
+		}),
+	},
+	"activeloans": {
+		private: true, help: "Your loans which have been taken",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			return p.ActiveLoans()
+		}),
+	},
+	"lendinghistory": {
+		args: "[start [end]]", private: true, help: "Your lending history, for the last month by default",
+		setup: func(fs *flag.FlagSet) runFunc {
+			limit := fs.Int64("limit", 0, "most entries to return, zero for no limit")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				d := []int64{time.Now().AddDate(0, -1, 0).Unix(), time.Now().Unix()}
+				given, err := dates(args)
+				if err != nil {
+					return nil, err
+				}
+				copy(d, given)
+				return p.LendingHistory(d[0], d[1], *limit)
+			}
+		},
+	},
+	"autorenew": {
+		args: "<loan number>", min: 1, private: true, help: "Toggles the auto renew setting of a loan",
+		setup: simple(func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+			n, err := integer(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return p.ToggleAutoRenew(n)
+		}),
+	},
+}
+
+// order is the buy or sell command
+func order(side string) command {
+	return command{
+		args: "<pair> <rate> <amount>", min: 3, private: true, help: "Places a limit " + side + " order",
+		setup: func(fs *flag.FlagSet) runFunc {
+			postOnly := fs.Bool("post-only", false, "only place the order if nothing fills immediately")
+			ioc := fs.Bool("ioc", false, "immediate or cancel, anything not filled straight away is cancelled")
+			fok := fs.Bool("fok", false, "fill or kill, the order is cancelled unless it fills completely")
+			return func(p *poloniex.Poloniex, args []string) (interface{}, error) {
+				pair := strings.ToUpper(args[0])
+				rate, amount, err := rateAmount(args[1], args[2])
+				if err != nil {
+					return nil, err
+				}
+				if side == "buy" {
+					switch {
+					case *postOnly:
+						return p.BuyPostOnly(pair, rate, amount)
+					case *ioc:
+						return p.BuyImmediateOrCancel(pair, rate, amount)
+					case *fok:
+						return p.BuyFillKill(pair, rate, amount)
+					}
+					return p.Buy(pair, rate, amount)
+				}
+				switch {
+				case *postOnly:
+					return p.SellPostOnly(pair, rate, amount)
+				case *ioc:
+					return p.SellImmediateOrCancel(pair, rate, amount)
+				case *fok:
+					return p.SellFillKill(pair, rate, amount)
+				}
+				return p.Sell(pair, rate, amount)
+			}
+		},
+	}
+}
+
+func rateAmount(r, a string) (rate, amount float64, err error) {
+	if rate, err = strconv.ParseFloat(r, 64); err != nil {
+		return 0, 0, errors.Wrap(err, "bad rate")
+	}
+	if amount, err = strconv.ParseFloat(a, 64); err != nil {
+		return 0, 0, errors.Wrap(err, "bad amount")
+	}
+	return
+}
+
+func integer(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, errors.Wrap(err, "bad number "+s)
+}
+
+func tail(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	return args[1:]
+}
+
+// dates parses up to two times as UNIX timestamps
+func dates(args []string) ([]int64, error) {
+	d := []int64{}
+	for i, a := range args {
+		if i == 2 {
+			break
+		}
+		t, err := parseTime(a)
+		if err != nil {
+			return nil, err
+		}
+		d = append(d, t.Unix())
+	}
+	return d, nil
+}
+
+// parseTime takes a UNIX timestamp, an RFC 3339 time or a date
+func parseTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	return t, errors.Wrap(err, "bad time "+s+", use a UNIX timestamp, RFC 3339 or 2006-01-02")
+}
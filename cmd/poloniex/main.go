@@ -0,0 +1,113 @@
+// Command poloniex is a command line client for the whole Poloniex API.
+//
+// Usage:
+//
+//	poloniex [flags] <command> [command flags] [arguments]
+//
+// Credentials are taken from -key and -secret, a -config file, or the POLONIEX_KEY and POLONIEX_SECRET
+// environment variables, in that order. Run "poloniex help" for the list of commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	poloniex "github.com/pharrisee/poloniex-api"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	flags := flag.NewFlagSet("poloniex", flag.ExitOnError)
+	key := flags.String("key", "", "API key")
+	secret := flags.String("secret", "", "API secret")
+	config := flags.String("config", "", "JSON file holding the key and secret")
+	format := flags.String("format", "table", "output format: table, json or csv")
+	dryRun := flags.Bool("dry-run", false, "print the signed request instead of sending it")
+	verbose := flags.Bool("v", false, "log requests to stderr")
+	flags.Usage = usage(flags)
+	flags.Parse(os.Args[1:])
+
+	args := flags.Args()
+	if len(args) == 0 || args[0] == "help" {
+		flags.Usage()
+		return
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q, run \"poloniex help\" for the list\n", args[0])
+		os.Exit(2)
+	}
+	out, err := newOutput(os.Stdout, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	c := poloniex.Credentials{Key: *key, Secret: *secret}
+	if cmd.private && (c.Key == "" || c.Secret == "") {
+		if c, err = credentials(*config); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	options := []poloniex.Option{}
+	if *verbose {
+		options = append(options, poloniex.WithLogger(poloniex.NewTextLogger(os.Stderr, poloniex.LevelDebug)))
+	}
+	p := poloniex.NewREST(c.Key, c.Secret, options...)
+	if *dryRun {
+		p.DryRun(os.Stdout)
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	run := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: poloniex %s %s\n\n%s\n", args[0], cmd.args, cmd.help)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+	if len(fs.Args()) < cmd.min {
+		fs.Usage()
+		os.Exit(2)
+	}
+	v, err := run(p, fs.Args())
+	if errors.Cause(err) == poloniex.ErrDryRun {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := out.write(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// credentials reads the credentials from the config file if given, otherwise from the environment
+func credentials(config string) (poloniex.Credentials, error) {
+	if config != "" {
+		return poloniex.FileCredentials{Filename: config}.Credentials()
+	}
+	return poloniex.EnvCredentials{}.Credentials()
+}
+
+func usage(flags *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "usage: poloniex [flags] <command> [command flags] [arguments]\n\nflags:\n")
+		flags.PrintDefaults()
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "\ncommands:\n")
+		for _, name := range names {
+			c := commands[name]
+			fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, strings.SplitN(c.help, "\n", 2)[0])
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// output writes results in one of the supported formats
+	output struct {
+		w      io.Writer
+		format string
+	}
+
+	// table is a result flattened to rows of text
+	table struct {
+		header []string
+		rows   [][]string
+	}
+)
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case "table", "json", "csv":
+		return &output{w: w, format: format}, nil
+	}
+	return nil, errors.Errorf("unknown format %q, use table, json or csv", format)
+}
+
+func (o *output) write(v interface{}) error {
+	if o.format == "json" {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	t := tabulate(reflect.ValueOf(v))
+	if o.format == "csv" {
+		w := csv.NewWriter(o.w)
+		w.Write(t.header)
+		w.WriteAll(t.rows)
+		return w.Error()
+	}
+	w := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	for _, r := range t.rows {
+		fmt.Fprintln(w, strings.Join(r, "\t"))
+	}
+	return w.Flush()
+}
+
+// tabulate flattens a result into a table: slices give a row per element, maps a row per key
+// and structs a row per field, nested values which do not fit are written as JSON
+func tabulate(v reflect.Value) table {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return table{header: []string{"Value"}}
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if isStruct(v.Type().Elem()) {
+			t := table{header: fieldNames(v.Type().Elem())}
+			for i := 0; i < v.Len(); i++ {
+				t.rows = append(t.rows, fieldValues(v.Index(i)))
+			}
+			return t
+		}
+		t := table{header: []string{"Value"}}
+		for i := 0; i < v.Len(); i++ {
+			t.rows = append(t.rows, []string{cell(v.Index(i))})
+		}
+		return t
+	case reflect.Map:
+		t := table{header: []string{"Key", "Value"}}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return cell(keys[i]) < cell(keys[j]) })
+		for _, k := range keys {
+			e := v.MapIndex(k)
+			if isScalar(e.Type()) {
+				t.rows = append(t.rows, []string{cell(k), cell(e)})
+				continue
+			}
+			// nested values give rows of their own, prefixed by the key
+			inner := tabulate(e)
+			t.header = append([]string{"Key"}, inner.header...)
+			for _, r := range inner.rows {
+				t.rows = append(t.rows, append([]string{cell(k)}, r...))
+			}
+		}
+		return t
+	case reflect.Struct:
+		if _, ok := v.Interface().(time.Time); ok {
+			return table{header: []string{"Value"}, rows: [][]string{{cell(v)}}}
+		}
+		// a struct holding a single list is shown as that list
+		if v.NumField() == 1 && v.Field(0).Kind() == reflect.Slice {
+			return tabulate(v.Field(0))
+		}
+		t := table{header: []string{"Field", "Value"}}
+		names, values := fieldNames(v.Type()), fieldValues(v)
+		for i := range names {
+			t.rows = append(t.rows, []string{names[i], values[i]})
+		}
+		return t
+	}
+	return table{header: []string{"Value"}, rows: [][]string{{cell(v)}}}
+}
+
+func isStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr, reflect.Interface:
+		return false
+	case reflect.Struct:
+		return t == reflect.TypeOf(time.Time{})
+	}
+	return true
+}
+
+// fieldNames returns the exported fields of a struct, with embedded structs flattened
+func fieldNames(t reflect.Type) (names []string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			names = append(names, fieldNames(f.Type)...)
+		case f.PkgPath == "":
+			names = append(names, f.Name)
+		}
+	}
+	return
+}
+
+func fieldValues(v reflect.Value) (values []string) {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		switch {
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			values = append(values, fieldValues(v.Field(i))...)
+		case f.PkgPath == "":
+			values = append(values, cell(v.Field(i)))
+		}
+	}
+	return
+}
+
+func cell(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return number(v.Float())
+	case reflect.String:
+		return v.String()
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			if t.IsZero() {
+				return ""
+			}
+			return t.Format(time.RFC3339)
+		}
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+	}
+	if isScalar(v.Type()) {
+		return fmt.Sprint(v.Interface())
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(b)
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
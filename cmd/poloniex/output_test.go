@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pharrisee/poloniex-api"
+)
+
+func Example_output() {
+	balances := poloniex.Balances{
+		"BTC": {Available: 1.5, OnOrders: 0.25, BTCValue: 1.75},
+		"ETH": {Available: 10},
+	}
+	orders := poloniex.OpenOrders{
+		{OrderNumber: 1, Type: "buy", Rate: 9000, Amount: 0.5, Total: 4500},
+	}
+	for _, format := range []string{"table", "csv"} {
+		o, err := newOutput(os.Stdout, format)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		o.write(balances)
+		o.write(orders)
+	}
+	_, err := newOutput(os.Stdout, "xml")
+	fmt.Println(err)
+	// Output:
+	// Key  Field      Value
+	// BTC  Available  1.5
+	// BTC  OnOrders   0.25
+	// BTC  BTCValue   1.75
+	// ETH  Available  10
+	// ETH  OnOrders   0
+	// ETH  BTCValue   0
+	// OrderNumber  Type  Rate  StartingAmount  Amount  Total  Date  Margin
+	// 1            buy   9000  0               0.5     4500         false
+	// Key,Field,Value
+	// BTC,Available,1.5
+	// BTC,OnOrders,0.25
+	// BTC,BTCValue,1.75
+	// ETH,Available,10
+	// ETH,OnOrders,0
+	// ETH,BTCValue,0
+	// OrderNumber,Type,Rate,StartingAmount,Amount,Total,Date,Margin
+	// 1,buy,9000,0,0.5,4500,,false
+	// unknown format "xml", use table, json or csv
+}
@@ -0,0 +1,296 @@
+package poloniex
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultKeyVar and DefaultSecretVar are the environment variables EnvCredentials reads by default
+	DefaultKeyVar    = "POLONIEX_KEY"
+	DefaultSecretVar = "POLONIEX_SECRET"
+
+	// encryptedIterations is the PBKDF2 work factor for new encrypted credential files
+	encryptedIterations = 200000
+	// maxEncryptedIterations bounds the work factor read from a file, so a corrupt one cannot hang the process
+	maxEncryptedIterations = 10000000
+)
+
+type (
+	// Credentials are an API key and secret, printing them shows only the start of the key
+	Credentials struct {
+		Key    string `json:"key"`
+		Secret string `json:"secret"`
+	}
+
+	// CredentialProvider supplies the credentials a client signs private requests with
+	CredentialProvider interface {
+		Credentials() (Credentials, error)
+	}
+
+	// EnvCredentials reads credentials from environment variables, POLONIEX_KEY and POLONIEX_SECRET by default
+	EnvCredentials struct {
+		KeyVar    string
+		SecretVar string
+	}
+
+	// FileCredentials reads credentials from a plaintext JSON file with key and secret fields, as used by NewWithConfig
+	FileCredentials struct {
+		Filename string
+	}
+
+	// EncryptedFileCredentials reads credentials from a file written by WriteEncryptedCredentials.
+	// Passphrase is asked for the passphrase each time the file is read, so it need not be held in memory.
+	EncryptedFileCredentials struct {
+		Filename   string
+		Passphrase func() ([]byte, error)
+	}
+
+	encryptedCredentials struct {
+		Version    int    `json:"version"`
+		KDF        string `json:"kdf"`
+		Iterations int    `json:"iterations"`
+		Salt       []byte `json:"salt"`
+		Nonce      []byte `json:"nonce"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+)
+
+// String shows the start of the key and hides the secret
+func (c Credentials) String() string {
+	return fmt.Sprintf("Credentials{Key: %s, Secret: %s}", redactKey(c.Key), redacted(c.Secret))
+}
+
+// GoString is as String, so %#v hides the secret too
+func (c Credentials) GoString() string {
+	return c.String()
+}
+
+func redactKey(key string) string {
+	if len(key) <= 8 {
+		return redacted(key)
+	}
+	return key[:8] + "…"
+}
+
+func redacted(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return "[REDACTED]"
+}
+
+// redact removes the client's credentials from text about to be written out
+func (p *Poloniex) redact(s string) string {
+	c := p.getCredentials()
+	if c.Secret != "" {
+		s = strings.Replace(s, c.Secret, "[REDACTED]", -1)
+	}
+	if c.Key != "" {
+		s = strings.Replace(s, c.Key, redactKey(c.Key), -1)
+	}
+	return s
+}
+
+// Credentials reads the credentials from the environment
+func (e EnvCredentials) Credentials() (c Credentials, err error) {
+	keyVar, secretVar := e.KeyVar, e.SecretVar
+	if keyVar == "" {
+		keyVar = DefaultKeyVar
+	}
+	if secretVar == "" {
+		secretVar = DefaultSecretVar
+	}
+	c = Credentials{Key: os.Getenv(keyVar), Secret: os.Getenv(secretVar)}
+	if c.Key == "" || c.Secret == "" {
+		return Credentials{}, errors.Errorf("%s and %s must both be set", keyVar, secretVar)
+	}
+	return c, nil
+}
+
+// Credentials reads the credentials from the file
+func (f FileCredentials) Credentials() (c Credentials, err error) {
+	b, err := ioutil.ReadFile(f.Filename)
+	if err != nil {
+		return c, errors.Wrap(err, "reading "+f.Filename+" failed")
+	}
+	if err = json.Unmarshal(b, &c); err != nil {
+		return c, errors.Wrap(err, "unmarshal of config failed")
+	}
+	return c, nil
+}
+
+// Credentials reads and decrypts the credentials from the file
+func (f EncryptedFileCredentials) Credentials() (c Credentials, err error) {
+	if f.Passphrase == nil {
+		return c, errors.New("no passphrase for encrypted credentials")
+	}
+	b, err := ioutil.ReadFile(f.Filename)
+	if err != nil {
+		return c, errors.Wrap(err, "reading "+f.Filename+" failed")
+	}
+	passphrase, err := f.Passphrase()
+	if err != nil {
+		return c, errors.Wrap(err, "reading passphrase failed")
+	}
+	return DecryptCredentials(b, passphrase)
+}
+
+// EncryptCredentials encrypts credentials with a key derived from passphrase, in the format EncryptedFileCredentials reads
+func EncryptCredentials(c Credentials, passphrase []byte) ([]byte, error) {
+	e := encryptedCredentials{Version: 1, KDF: "pbkdf2-sha256", Iterations: encryptedIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(e.Salt); err != nil {
+		return nil, errors.Wrap(err, "generating salt failed")
+	}
+	gcm, err := credentialCipher(passphrase, e.Salt, e.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	e.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, errors.Wrap(err, "generating nonce failed")
+	}
+	plain, err := json.Marshal(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal of credentials failed")
+	}
+	e.Ciphertext = gcm.Seal(nil, e.Nonce, plain, nil)
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// DecryptCredentials decrypts credentials encrypted by EncryptCredentials
+func DecryptCredentials(b, passphrase []byte) (c Credentials, err error) {
+	e := encryptedCredentials{}
+	if err = json.Unmarshal(b, &e); err != nil {
+		return c, errors.Wrap(err, "unmarshal of encrypted credentials failed")
+	}
+	if e.Version != 1 || e.KDF != "pbkdf2-sha256" {
+		return c, errors.Errorf("unsupported encrypted credentials version %d (%s)", e.Version, e.KDF)
+	}
+	if e.Iterations <= 0 || e.Iterations > maxEncryptedIterations {
+		return c, errors.Errorf("encrypted credentials have an invalid iteration count %d", e.Iterations)
+	}
+	gcm, err := credentialCipher(passphrase, e.Salt, e.Iterations)
+	if err != nil {
+		return c, err
+	}
+	if len(e.Nonce) != gcm.NonceSize() {
+		return c, errors.Errorf("encrypted credentials have a %d byte nonce, %d expected", len(e.Nonce), gcm.NonceSize())
+	}
+	plain, err := gcm.Open(nil, e.Nonce, e.Ciphertext, nil)
+	if err != nil {
+		return c, errors.New("decrypting credentials failed, the passphrase may be wrong")
+	}
+	if err = json.Unmarshal(plain, &c); err != nil {
+		return c, errors.Wrap(err, "unmarshal of credentials failed")
+	}
+	return c, nil
+}
+
+// WriteEncryptedCredentials writes credentials encrypted with passphrase to filename, readable only by the owner
+func WriteEncryptedCredentials(filename string, c Credentials, passphrase []byte) error {
+	b, err := EncryptCredentials(c, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, b, 0600); err != nil {
+		return errors.Wrap(err, "writing "+filename+" failed")
+	}
+	return nil
+}
+
+func credentialCipher(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256(passphrase, salt, iterations, 32))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cipher failed")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cipher failed")
+	}
+	return gcm, nil
+}
+
+// pbkdf2SHA256 derives a key from a passphrase as in RFC 8018, kept here to avoid depending on x/crypto
+func pbkdf2SHA256(passphrase, salt []byte, iterations, length int) []byte {
+	prf := hmac.New(sha256.New, passphrase)
+	key := []byte{}
+	for block := uint32(1); len(key) < length; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte{}, u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:length]
+}
+
+// NewWithProvider creates a client taking its credentials from provider, see RotateCredentials.
+// Unlike the other constructors it returns failing to read the credentials or fetch the markets as an error.
+func NewWithProvider(provider CredentialProvider, options ...Option) (*Poloniex, error) {
+	c, err := provider.Credentials()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading credentials failed")
+	}
+	p := newClient(options...)
+	p.SetCredentials(c)
+	p.credMutex.Lock()
+	p.provider = provider
+	p.credMutex.Unlock()
+	p.ws.Dial(apiURL, http.Header{})
+	if err := p.getMarkets(); err != nil {
+		p.ws.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetCredentials replaces the credentials used to sign private requests, requests already signed are unaffected
+func (p *Poloniex) SetCredentials(c Credentials) {
+	p.credMutex.Lock()
+	defer p.credMutex.Unlock()
+	p.credentials = c
+}
+
+// RotateCredentials reads the credentials from the client's provider again, for when keys have been rotated
+func (p *Poloniex) RotateCredentials() error {
+	p.credMutex.RLock()
+	provider := p.provider
+	p.credMutex.RUnlock()
+	if provider == nil {
+		return errors.New("client has no credential provider")
+	}
+	c, err := provider.Credentials()
+	if err != nil {
+		return errors.Wrap(err, "reading credentials failed")
+	}
+	p.SetCredentials(c)
+	return nil
+}
+
+func (p *Poloniex) getCredentials() Credentials {
+	p.credMutex.RLock()
+	defer p.credMutex.RUnlock()
+	return p.credentials
+}
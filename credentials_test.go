@@ -0,0 +1,42 @@
+package poloniex
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleEncryptCredentials() {
+	b, err := EncryptCredentials(Credentials{Key: "ABCDEFGH-IJKLMNOP", Secret: "0123456789abcdef"}, []byte("correct horse"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if _, err := DecryptCredentials(b, []byte("wrong horse")); err != nil {
+		fmt.Println(err)
+	}
+	c, err := DecryptCredentials(b, []byte("correct horse"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(c)
+
+	// damaged files are refused rather than decrypted
+	for _, damaged := range []string{
+		`{"version":1,"kdf":"pbkdf2-sha256","iterations":200000,"salt":"AAAA","nonce":"AAAA","ciphertext":"AAAA"}`,
+		`{"version":1,"kdf":"pbkdf2-sha256","iterations":0,"salt":"AAAA","nonce":"AAAA","ciphertext":"AAAA"}`,
+	} {
+		_, err := DecryptCredentials([]byte(damaged), []byte("correct horse"))
+		fmt.Println(err)
+	}
+	// Output:
+	// decrypting credentials failed, the passphrase may be wrong
+	// Credentials{Key: ABCDEFGH…, Secret: [REDACTED]}
+	// encrypted credentials have a 3 byte nonce, 12 expected
+	// encrypted credentials have an invalid iteration count 0
+}
+
+func ExampleNewWithProvider() {
+	// unlike NewWithConfig, a missing config is returned rather than stopping the program
+	_, err := NewWithProvider(FileCredentials{Filename: "missing.json"})
+	fmt.Println(err)
+	// Output: reading credentials failed: reading missing.json failed: open missing.json: no such file or directory
+}
@@ -1,6 +1,20 @@
 package poloniex
 
-import "github.com/chuckpreslar/emission"
+import (
+	"reflect"
+	"sync"
+
+	"github.com/chuckpreslar/emission"
+)
+
+// listeners fans an event out from a single emitter listener to any number of subscribers, each of which
+// can be removed on its own. The emitter's Off matches listeners by their code, which every method value
+// and every closure of one function share, so it would remove the listeners of every instance at once.
+type listeners struct {
+	mutex sync.Mutex
+	next  int
+	subs  map[string]map[int]reflect.Value
+}
 
 //On adds a listener to a specific event
 func (p *Poloniex) On(event interface{}, listener interface{}) *emission.Emitter {
@@ -16,3 +30,48 @@ func (p *Poloniex) Emit(event interface{}, arguments ...interface{}) *emission.E
 func (p *Poloniex) Off(event interface{}, listener interface{}) *emission.Emitter {
 	return p.emitter.Off(event, listener)
 }
+
+// listen adds listener, a func of one argument, to an event and returns a func which removes just that listener
+func (p *Poloniex) listen(event string, listener interface{}) (remove func()) {
+	l := &p.listeners
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.subs == nil {
+		l.subs = map[string]map[int]reflect.Value{}
+	}
+	if _, ok := l.subs[event]; !ok {
+		l.subs[event] = map[int]reflect.Value{}
+		p.On(event, func(arg interface{}) { l.dispatch(event, arg) })
+	}
+	l.next++
+	id := l.next
+	l.subs[event][id] = reflect.ValueOf(listener)
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		delete(l.subs[event], id)
+	}
+}
+
+// dispatch calls the subscribers of an event, each in its own goroutine as the emitter does
+func (l *listeners) dispatch(event string, arg interface{}) {
+	l.mutex.Lock()
+	fns := make([]reflect.Value, 0, len(l.subs[event]))
+	for _, fn := range l.subs[event] {
+		fns = append(fns, fn)
+	}
+	l.mutex.Unlock()
+	wg := sync.WaitGroup{}
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn reflect.Value) {
+			defer wg.Done()
+			v := reflect.ValueOf(arg)
+			if arg == nil {
+				v = reflect.Zero(fn.Type().In(0))
+			}
+			fn.Call([]reflect.Value{v})
+		}(fn)
+	}
+	wg.Wait()
+}
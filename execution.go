@@ -0,0 +1,411 @@
+package poloniex
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// ExecutionConfig describes a parent order to be worked by TWAP or VWAP
+	ExecutionConfig struct {
+		Pair   string
+		Side   string
+		Amount float64
+		// Duration is the time the parent order is spread over
+		Duration time.Duration
+		// Slices is the number of child orders, defaults to one a minute
+		Slices int
+		// PostOnly places and moves child orders as post only
+		PostOnly bool
+		// LimitRate is the highest rate to buy at, or the lowest to sell at, zero is no limit
+		LimitRate float64
+		// Reprice is how often child orders are checked and moved to the top of the book, defaults to 10 seconds
+		Reprice time.Duration
+		// History is how far back VWAP looks for its volume profile, defaults to a week
+		History time.Duration
+	}
+
+	// ExecutionProgress reports how far an execution has got, it is emitted as an "execution-progress" event
+	ExecutionProgress struct {
+		Pair         string
+		Side         string
+		Target       float64
+		Filled       float64
+		Remaining    float64
+		AveragePrice float64
+		SlicesSent   int
+		Slices       int
+		Paused       bool
+		Done         bool
+		Children     []TrackedOrder
+	}
+
+	// Execution works a parent order as a schedule of child limit orders
+	Execution struct {
+		p        *Poloniex
+		config   ExecutionConfig
+		schedule []float64
+		tracker  *OrderTracker
+		mutex    sync.Mutex
+		children []int64
+		requeued map[int64]bool
+		final    []TrackedOrder
+		sent     int
+		carry    float64
+		paused   bool
+		finished bool
+		cancel   context.CancelFunc
+		done     chan struct{}
+	}
+)
+
+// TWAP works a parent order as equal slices spread evenly over the configured duration
+func (p *Poloniex) TWAP(config ExecutionConfig) (*Execution, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	schedule := make([]float64, config.Slices)
+	for i := range schedule {
+		schedule[i] = config.Amount / float64(config.Slices)
+	}
+	return p.execute(config, schedule), nil
+}
+
+// VWAP works a parent order in slices sized in proportion to the volume traded at the same time of day
+// over the configured history, taken from ChartData
+func (p *Poloniex) VWAP(config ExecutionConfig) (*Execution, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	cd, err := p.ChartDataPeriod(config.Pair, now.Add(-config.History), now, 300)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching chart data failed")
+	}
+	return p.execute(config, volumeSchedule(cd, now, config.Duration, config.Slices, config.Amount)), nil
+}
+
+func (c *ExecutionConfig) validate() error {
+	if c.Side != "buy" && c.Side != "sell" {
+		return errors.New("execution side must be buy or sell")
+	}
+	if c.Amount <= 0 || c.Duration <= 0 {
+		return errors.New("execution amount and duration must be positive")
+	}
+	if c.Slices <= 0 {
+		c.Slices = int(math.Max(1, math.Ceil(c.Duration.Minutes())))
+	}
+	if c.Reprice <= 0 {
+		c.Reprice = 10 * time.Second
+	}
+	if c.History <= 0 {
+		c.History = 7 * 24 * time.Hour
+	}
+	return nil
+}
+
+// volumeSchedule splits amount across slices in proportion to the historical volume at each slice's time of day
+func volumeSchedule(cd ChartData, start time.Time, duration time.Duration, slices int, amount float64) []float64 {
+	const day = 24 * 60 * 60
+	tod := func(t int64) int64 { return ((t % day) + day) % day }
+	step := duration / time.Duration(slices)
+	weights := make([]float64, slices)
+	total := 0.0
+	for i := range weights {
+		from := tod(start.Add(step * time.Duration(i)).Unix())
+		to := tod(start.Add(step * time.Duration(i+1)).Unix())
+		for _, v := range cd {
+			t := tod(v.Date)
+			in := t >= from && t < to
+			if from > to {
+				// the slice wraps past midnight
+				in = t >= from || t < to
+			}
+			if in {
+				weights[i] += v.Volume
+			}
+		}
+		total += weights[i]
+	}
+	schedule := make([]float64, slices)
+	for i := range schedule {
+		if total > 0 {
+			schedule[i] = amount * weights[i] / total
+		} else {
+			schedule[i] = amount / float64(slices)
+		}
+	}
+	return schedule
+}
+
+func (p *Poloniex) execute(config ExecutionConfig, schedule []float64) *Execution {
+	ctx, cancel := context.WithCancel(context.Background())
+	x := &Execution{
+		p:        p,
+		config:   config,
+		schedule: schedule,
+		tracker:  p.OrderTracker(),
+		requeued: map[int64]bool{},
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go x.run(ctx)
+	return x
+}
+
+// Pause stops placing and repricing child orders, resting children stay on the book
+func (x *Execution) Pause() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.paused = true
+}
+
+// Resume carries on after Pause, any slices missed while paused are placed straight away
+func (x *Execution) Resume() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.paused = false
+}
+
+// Cancel stops the execution and cancels any resting child orders, waiting for it to finish
+func (x *Execution) Cancel() ExecutionProgress {
+	x.cancel()
+	return x.Wait()
+}
+
+// Done is closed when the execution finishes
+func (x *Execution) Done() <-chan struct{} {
+	return x.done
+}
+
+// Wait waits for the execution to finish and returns the final progress
+func (x *Execution) Wait() ExecutionProgress {
+	<-x.done
+	return x.Progress()
+}
+
+// Progress reports how far the execution has got
+func (x *Execution) Progress() (pr ExecutionProgress) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	pr = ExecutionProgress{
+		Pair: x.config.Pair, Side: x.config.Side, Target: x.config.Amount,
+		SlicesSent: x.sent, Slices: len(x.schedule), Paused: x.paused, Done: x.finished,
+	}
+	children := x.final
+	if !x.finished {
+		children = x.current()
+	}
+	total := 0.0
+	for _, o := range children {
+		pr.Children = append(pr.Children, o)
+		pr.Filled += o.Filled
+		total += o.Total
+	}
+	pr.Remaining = math.Max(0, pr.Target-pr.Filled)
+	if pr.Filled > 0 {
+		pr.AveragePrice = total / pr.Filled
+	}
+	return
+}
+
+// current returns the state of the children from the tracker, the mutex must be held
+func (x *Execution) current() []TrackedOrder {
+	children := []TrackedOrder{}
+	for _, n := range x.children {
+		if o, ok := x.tracker.Order(n); ok {
+			children = append(children, o)
+		}
+	}
+	return children
+}
+
+func (x *Execution) run(ctx context.Context) {
+	defer close(x.done)
+	step := x.config.Duration / time.Duration(len(x.schedule))
+	start := time.Now()
+	reprice := time.NewTicker(x.config.Reprice)
+	defer reprice.Stop()
+	for {
+		x.mutex.Lock()
+		paused, sent := x.paused, x.sent
+		x.mutex.Unlock()
+
+		var due <-chan time.Time
+		if sent < len(x.schedule) && !paused {
+			due = time.After(time.Until(start.Add(step * time.Duration(sent))))
+		}
+		select {
+		case <-ctx.Done():
+			x.cancelChildren()
+			x.finish()
+			return
+		case <-due:
+			x.mutex.Lock()
+			x.sent++
+			x.mutex.Unlock()
+			x.placeSlice(x.schedule[sent])
+		case <-reprice.C:
+			if paused {
+				continue
+			}
+			if err := x.tracker.Poll(); err != nil {
+				x.p.Emit("execution-error", err)
+			}
+			x.requeueCancelled()
+			if sent == len(x.schedule) {
+				// retry whatever could not be placed with the last slice, or was cancelled since
+				x.placeSlice(0)
+			}
+			x.repriceChildren()
+			pr := x.Progress()
+			x.p.Emit("execution-progress", pr)
+			if pr.SlicesSent == pr.Slices && pr.Remaining <= filledEpsilon {
+				x.finish()
+				return
+			}
+		}
+	}
+}
+
+func (x *Execution) finish() {
+	x.mutex.Lock()
+	x.finished = true
+	// keep the final state of the children and stop tracking them, the client's tracker outlives the execution
+	x.final = x.current()
+	for _, n := range x.children {
+		x.tracker.Forget(n)
+	}
+	x.mutex.Unlock()
+	x.p.Emit("execution-progress", x.Progress())
+}
+
+// placeSlice places a child order at the top of the book, along with anything earlier slices failed to place
+func (x *Execution) placeSlice(amount float64) {
+	x.mutex.Lock()
+	amount += x.carry
+	x.carry = 0
+	x.mutex.Unlock()
+	if amount <= filledEpsilon {
+		return
+	}
+	b, err := x.place(amount)
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if err != nil {
+		x.carry = amount
+		x.p.Emit("execution-error", err)
+		return
+	}
+	x.children = append(x.children, b.OrderNumber)
+}
+
+// requeueCancelled carries what children cancelled outside the execution left unfilled, by the kill switch
+// or by hand, so it is placed again with the next slice
+func (x *Execution) requeueCancelled() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	for _, o := range x.current() {
+		if o.State == OrderCancelled && !x.requeued[o.OrderNumber] {
+			x.requeued[o.OrderNumber] = true
+			x.carry += math.Max(0, o.Remaining)
+		}
+	}
+}
+
+// place places a single child order at the top of the book
+func (x *Execution) place(amount float64) (b Buy, err error) {
+	rate, err := x.topOfBook()
+	if err != nil {
+		return
+	}
+	switch {
+	case x.config.Side == "buy" && x.config.PostOnly:
+		b, err = x.tracker.BuyPostOnly(x.config.Pair, rate, amount)
+	case x.config.Side == "buy":
+		b, err = x.tracker.Buy(x.config.Pair, rate, amount)
+	case x.config.PostOnly:
+		var s Sell
+		s, err = x.tracker.SellPostOnly(x.config.Pair, rate, amount)
+		b = s.Buy
+	default:
+		var s Sell
+		s, err = x.tracker.Sell(x.config.Pair, rate, amount)
+		b = s.Buy
+	}
+	if err != nil {
+		err = errors.Wrap(err, "placing child order failed")
+	}
+	return
+}
+
+// repriceChildren moves resting children which are no longer at the top of the book
+func (x *Execution) repriceChildren() {
+	rate, err := x.topOfBook()
+	if err != nil {
+		x.p.Emit("execution-error", err)
+		return
+	}
+	x.mutex.Lock()
+	children := append([]int64{}, x.children...)
+	x.mutex.Unlock()
+	for i, n := range children {
+		o, ok := x.tracker.Order(n)
+		if !ok || o.State.Done() || o.Rate == rate {
+			continue
+		}
+		move := x.tracker.Move
+		if x.config.PostOnly {
+			move = x.tracker.MovePostOnly
+		}
+		m, err := move(o.OrderNumber, rate)
+		if err != nil {
+			x.p.Emit("execution-error", errors.Wrap(err, "moving child order failed"))
+			continue
+		}
+		x.mutex.Lock()
+		x.children[i] = m.OrderNumber
+		x.mutex.Unlock()
+	}
+}
+
+func (x *Execution) cancelChildren() {
+	x.mutex.Lock()
+	children := append([]int64{}, x.children...)
+	x.mutex.Unlock()
+	for _, n := range children {
+		if o, ok := x.tracker.Order(n); ok && !o.State.Done() {
+			if _, err := x.tracker.Cancel(o.OrderNumber); err != nil {
+				x.p.Emit("execution-error", errors.Wrap(err, "cancelling child order failed"))
+			}
+		}
+	}
+}
+
+// topOfBook returns the best bid for buys or best ask for sells, held within the limit rate
+func (x *Execution) topOfBook() (float64, error) {
+	ob, err := x.p.OrderBook(x.config.Pair)
+	if err != nil {
+		return 0, errors.Wrap(err, "fetching order book failed")
+	}
+	side := ob.Bids
+	if x.config.Side == "sell" {
+		side = ob.Asks
+	}
+	if len(side) == 0 {
+		return 0, errors.New("order book is empty")
+	}
+	rate := side[0].Rate
+	if x.config.LimitRate > 0 {
+		if x.config.Side == "buy" {
+			rate = math.Min(rate, x.config.LimitRate)
+		} else {
+			rate = math.Max(rate, x.config.LimitRate)
+		}
+	}
+	return rate, nil
+}
@@ -0,0 +1,56 @@
+package poloniex
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+func ExampleExecution() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	orders := int64(0)
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			switch req.Command {
+			case "returnOrderBook":
+				return &Response{Status: 200, Body: `{"asks":[["7001",1]],"bids":[["7000",1]],"isFrozen":"0","seq":1}`}, nil
+			case "buy":
+				// the first child rests on the book, the second fills straight away
+				n := atomic.AddInt64(&orders, 1)
+				fmt.Println("buy", req.Params.Get("amount"))
+				if n == 1 {
+					return &Response{Status: 200, Body: `{"orderNumber":"1","resultingTrades":[]}`}, nil
+				}
+				return &Response{Status: 200, Body: fmt.Sprintf(`{"orderNumber":"%d","resultingTrades":[`+
+					`{"amount":"%s","rate":"7000","total":"7000","tradeID":"9","type":"buy"}]}`, n, req.Params.Get("amount"))}, nil
+			case "returnOpenOrders":
+				return &Response{Status: 200, Body: `{"USDT_BTC":[{"orderNumber":"1","type":"buy","rate":"7000","amount":"1"}]}`}, nil
+			}
+			return nil, fmt.Errorf("unexpected %s", req.Command)
+		}
+	})
+	x, err := p.TWAP(ExecutionConfig{Pair: "USDT_BTC", Side: "buy", Amount: 1, Duration: time.Millisecond, Slices: 1,
+		Reprice: 20 * time.Millisecond})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for len(x.Progress().Children) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the resting child is cancelled outside the execution, what it left unfilled is placed again
+	p.Emit("account-update", WSAccountUpdate{OrderNumber: 1, Reason: "cancel"})
+	pr := x.Wait()
+	for _, o := range pr.Children {
+		fmt.Println(o.OrderNumber, o.State, o.Filled)
+	}
+	fmt.Println(pr.Filled, pr.Remaining, pr.Done)
+	// Output:
+	// buy 1.00000000
+	// buy 1.00000000
+	// 1 cancelled 0
+	// 2 filled 1
+	// 1 0 true
+}
@@ -0,0 +1,186 @@
+package poloniex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// DepositChange is emitted as a "deposit-confirmations" event when a deposit is first seen or gains confirmations,
+	// and as a "deposit-complete" event when it completes
+	DepositChange struct {
+		Deposit  Deposit
+		Previous Deposit
+		New      bool
+	}
+
+	// WithdrawalChange is emitted as a "withdrawal-status" event when a withdrawal is first seen or its status changes
+	WithdrawalChange struct {
+		Withdrawal Withdrawal
+		Previous   Withdrawal
+		New        bool
+	}
+
+	// FundingWatcher polls the deposit and withdrawal history and emits events as they change.
+	// What has been seen is saved to a file, if one is given, so restarts do not fire events again.
+	// Failures are emitted as "funding-error" events.
+	FundingWatcher struct {
+		p        *Poloniex
+		filename string
+		lookback time.Duration
+		mutex    sync.Mutex
+		state    fundingState
+	}
+
+	fundingState struct {
+		// Seeded is set once the history has been read, events are only fired for changes after that
+		Seeded      bool
+		Deposits    map[string]Deposit
+		Withdrawals map[string]Withdrawal
+	}
+)
+
+// NewFundingWatcher creates a watcher for the client, loading what has been seen from filename.
+// Pass an empty filename to keep state in memory only. lookback is how much history is polled, defaults to 30 days.
+// The first poll without saved state records the history without firing events.
+func NewFundingWatcher(p *Poloniex, filename string, lookback time.Duration) (*FundingWatcher, error) {
+	if lookback <= 0 {
+		lookback = 30 * 24 * time.Hour
+	}
+	f := &FundingWatcher{p: p, filename: filename, lookback: lookback, state: fundingState{
+		Deposits: map[string]Deposit{}, Withdrawals: map[string]Withdrawal{},
+	}}
+	if filename == "" {
+		return f, nil
+	}
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading "+filename+" failed")
+	}
+	if err := json.Unmarshal(b, &f.state); err != nil {
+		return nil, errors.Wrap(err, "unmarshal of funding state failed")
+	}
+	if f.state.Deposits == nil {
+		f.state.Deposits = map[string]Deposit{}
+	}
+	if f.state.Withdrawals == nil {
+		f.state.Withdrawals = map[string]Withdrawal{}
+	}
+	return f, nil
+}
+
+// Run polls every interval until ctx is done, interval defaults to a minute
+func (f *FundingWatcher) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := f.Poll(); err != nil {
+			f.p.Emit("funding-error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Poll fetches the history once and emits events for anything which has changed since the last poll
+func (f *FundingWatcher) Poll() error {
+	now := time.Now()
+	dw, err := f.p.DepositsWithdrawalsRange(now.Add(-f.lookback), now, 0)
+	if err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	deposits, withdrawals := f.update(dw, now)
+	err = f.save()
+	f.mutex.Unlock()
+
+	for _, c := range deposits {
+		if isComplete(c.Deposit.Status) {
+			f.p.Emit("deposit-complete", c)
+		} else {
+			f.p.Emit("deposit-confirmations", c)
+		}
+	}
+	for _, c := range withdrawals {
+		f.p.Emit("withdrawal-status", c)
+	}
+	return err
+}
+
+// update merges the history into the state and returns the changes, the mutex must be held
+func (f *FundingWatcher) update(dw DepositsWithdrawals, now time.Time) (deposits []DepositChange, withdrawals []WithdrawalChange) {
+	for _, d := range dw.Deposits {
+		key := d.Currency + ":" + d.TXID
+		previous, seen := f.state.Deposits[key]
+		f.state.Deposits[key] = d
+		if seen && previous.Confirmations == d.Confirmations && previous.Status == d.Status {
+			continue
+		}
+		if seen && isComplete(previous.Status) {
+			// a completed deposit only changes if the exchange rewrites history
+			continue
+		}
+		deposits = append(deposits, DepositChange{Deposit: d, Previous: previous, New: !seen})
+	}
+	for _, w := range dw.Withdrawals {
+		key := fmt.Sprintf("%d", w.WithdrawalNumber)
+		previous, seen := f.state.Withdrawals[key]
+		f.state.Withdrawals[key] = w
+		if seen && previous.Status == w.Status {
+			continue
+		}
+		withdrawals = append(withdrawals, WithdrawalChange{Withdrawal: w, Previous: previous, New: !seen})
+	}
+	// forget what has dropped out of the polled history
+	since := now.Add(-f.lookback - 24*time.Hour).Unix()
+	for k, d := range f.state.Deposits {
+		if d.Timestamp < since {
+			delete(f.state.Deposits, k)
+		}
+	}
+	for k, w := range f.state.Withdrawals {
+		if w.Timestamp < since {
+			delete(f.state.Withdrawals, k)
+		}
+	}
+	if !f.state.Seeded {
+		f.state.Seeded = true
+		return nil, nil
+	}
+	return
+}
+
+// save writes the state to the file, replacing it atomically, the mutex must be held
+func (f *FundingWatcher) save() error {
+	if f.filename == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(f.state, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal of funding state failed")
+	}
+	tmp := f.filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "writing "+tmp+" failed")
+	}
+	if err := os.Rename(tmp, f.filename); err != nil {
+		return errors.Wrap(err, "replacing "+f.filename+" failed")
+	}
+	return nil
+}
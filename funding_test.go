@@ -0,0 +1,73 @@
+package poloniex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func ExampleFundingWatcher() {
+	dir, err := ioutil.TempDir("", "funding")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "funding.json")
+
+	ts := time.Now().Add(-time.Hour).Unix()
+	history := func(confirmations int, status, withdrawal string) string {
+		return fmt.Sprintf(`{
+			"deposits":[{"currency":"BTC","amount":"1","confirmations":%d,"txid":"a1","timestamp":%d,"status":"%s"}],
+			"withdrawals":[{"withdrawalNumber":7,"currency":"BTC","amount":"0.5","timestamp":%d,"status":"%s"}]}`,
+			confirmations, ts, status, ts, withdrawal)
+	}
+	body := history(1, "PENDING", "PENDING")
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			if req.Command == "returnDepositsWithdrawals" {
+				return &Response{Status: 200, Body: body}, nil
+			}
+			return next(req)
+		}
+	})
+	p.On("deposit-confirmations", func(c DepositChange) {
+		fmt.Println("confirmations:", c.Deposit.TXID, c.Previous.Confirmations, "->", c.Deposit.Confirmations)
+	})
+	p.On("deposit-complete", func(c DepositChange) {
+		fmt.Println("complete:", c.Deposit.TXID, c.Deposit.Amount)
+	})
+	p.On("withdrawal-status", func(c WithdrawalChange) {
+		fmt.Println("withdrawal:", c.Withdrawal.WithdrawalNumber, c.Previous.Status, "->", c.Withdrawal.Status)
+	})
+
+	// the first poll only records the history
+	f, err := NewFundingWatcher(p, filename, 0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(f.Poll())
+	body = history(2, "PENDING", "PENDING")
+	fmt.Println(f.Poll())
+
+	// a restarted watcher picks up from the saved state
+	body = history(3, "COMPLETE", "COMPLETE: 0123abcd")
+	f, err = NewFundingWatcher(p, filename, 0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(f.Poll())
+	fmt.Println(f.Poll())
+	// Output:
+	// <nil>
+	// confirmations: a1 1 -> 2
+	// <nil>
+	// complete: a1 1
+	// withdrawal: 7 PENDING -> COMPLETE: 0123abcd
+	// <nil>
+	// <nil>
+}
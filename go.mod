@@ -14,6 +14,6 @@ require (
 	github.com/recws-org/recws v1.0.1
 	github.com/streamrail/concurrent-map v0.0.0-20160823150647-8bf1e9bacbf6 // indirect
 	github.com/ugorji/go v1.1.7 // indirect
-	golang.org/x/sys v0.0.0-20200107162124-548cf772de50 // indirect
+	golang.org/x/sys v0.0.0-20200107162124-548cf772de50
 	gopkg.in/beatgammit/turnpike.v2 v2.0.0-20170911161258-573f579df7ee
 )
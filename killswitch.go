@@ -0,0 +1,285 @@
+package poloniex
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrHalted is returned by order placement while the client is halted by a kill switch
+var ErrHalted = errors.New("trading is halted")
+
+// placementCommands are the private commands blocked while the client is halted
+var placementCommands = map[string]bool{
+	"buy":             true,
+	"sell":            true,
+	"moveOrder":       true,
+	"marginBuy":       true,
+	"marginSell":      true,
+	"createLoanOffer": true,
+}
+
+type (
+	// KillSwitchConfig sets when a KillSwitch trips and what it does
+	KillSwitchConfig struct {
+		// Timeout is how long without a Heartbeat before tripping, zero turns the heartbeat check off
+		Timeout time.Duration
+		// Interval is how often the heartbeat and limits are checked, defaults to 10 seconds
+		Interval time.Duration
+		// Currency is the currency MaxLoss and MaxExposure are in, defaults to BTC
+		Currency string
+		// MaxLoss trips when the portfolio falls by more than this from its value when armed, zero is no limit
+		MaxLoss float64
+		// MaxExposure trips when open orders and margin positions are worth more than this, zero is no limit
+		MaxExposure float64
+		// CloseMargin closes every margin position when tripped
+		CloseMargin bool
+	}
+
+	// KillSwitchTrip is emitted as a "killswitch-tripped" event with what was done when a kill switch tripped
+	KillSwitchTrip struct {
+		Reason        string
+		TS            time.Time
+		Orders        CancelReport
+		LoanOffers    int
+		MarginsClosed []string
+		Errors        []error
+	}
+
+	// KillSwitch is a dead man's switch for a trading process. When heartbeats stop or a limit is breached
+	// it halts order placement through the client, then cancels every open order and loan offer.
+	// Placement stays blocked, returning ErrHalted, until Rearm is called.
+	// Failures while checking are emitted as "killswitch-error" events.
+	KillSwitch struct {
+		p        *Poloniex
+		config   KillSwitchConfig
+		mutex    sync.Mutex
+		beat     time.Time
+		baseline float64
+		tripped  bool
+	}
+)
+
+// halt blocks order placement with the reason given
+func (p *Poloniex) halt(reason string) {
+	p.haltMutex.Lock()
+	defer p.haltMutex.Unlock()
+	p.halted = reason
+}
+
+// Halted reports whether order placement is blocked, and why
+func (p *Poloniex) Halted() (reason string, halted bool) {
+	p.haltMutex.RLock()
+	defer p.haltMutex.RUnlock()
+	return p.halted, p.halted != ""
+}
+
+// checkHalted fails placement commands while the client is halted
+func (p *Poloniex) checkHalted(command string) error {
+	if !placementCommands[command] {
+		return nil
+	}
+	if reason, halted := p.Halted(); halted {
+		return errors.Wrap(ErrHalted, reason)
+	}
+	return nil
+}
+
+// NewKillSwitch creates a kill switch for the client, it is armed once Run starts
+func NewKillSwitch(p *Poloniex, config KillSwitchConfig) *KillSwitch {
+	if config.Interval <= 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.Currency == "" {
+		config.Currency = "BTC"
+	}
+	return &KillSwitch{p: p, config: config, beat: time.Now()}
+}
+
+// Heartbeat tells the kill switch the application is alive
+func (k *KillSwitch) Heartbeat() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.beat = time.Now()
+}
+
+// Tripped reports whether the kill switch has tripped and not been re-armed
+func (k *KillSwitch) Tripped() bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.tripped
+}
+
+// Rearm allows order placement again, the heartbeat and the loss baseline start afresh
+func (k *KillSwitch) Rearm() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.tripped = false
+	k.beat = time.Now()
+	k.baseline = 0
+	k.p.halt("")
+}
+
+// Run checks the heartbeat and limits every Interval until ctx is done
+func (k *KillSwitch) Run(ctx context.Context) error {
+	k.Heartbeat()
+	t := time.NewTicker(k.config.Interval)
+	defer t.Stop()
+	for {
+		if err := k.Check(); err != nil {
+			k.p.Emit("killswitch-error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Check trips the kill switch if the heartbeat has stopped or a limit is breached
+func (k *KillSwitch) Check() error {
+	k.mutex.Lock()
+	tripped, beat := k.tripped, k.beat
+	k.mutex.Unlock()
+	if tripped {
+		return nil
+	}
+	if k.config.Timeout > 0 && time.Since(beat) > k.config.Timeout {
+		k.Trip("no heartbeat for " + time.Since(beat).Round(time.Second).String())
+		return nil
+	}
+	if k.config.MaxLoss > 0 {
+		portfolio, err := k.p.Portfolio(k.config.Currency)
+		if err != nil {
+			return errors.Wrap(err, "valuing portfolio failed")
+		}
+		k.mutex.Lock()
+		if k.baseline == 0 {
+			k.baseline = portfolio.Total
+		}
+		loss := k.baseline - portfolio.Total
+		k.mutex.Unlock()
+		if loss > k.config.MaxLoss {
+			k.Trip(fmt.Sprintf("loss of %.8f %s breaches the limit", loss, k.config.Currency))
+			return nil
+		}
+	}
+	if k.config.MaxExposure > 0 {
+		exposure, err := k.exposure()
+		if err != nil {
+			return err
+		}
+		if exposure > k.config.MaxExposure {
+			k.Trip(fmt.Sprintf("exposure of %.8f %s breaches the limit", exposure, k.config.Currency))
+		}
+	}
+	return nil
+}
+
+// exposure values open orders and margin positions in the configured currency
+func (k *KillSwitch) exposure() (float64, error) {
+	prices, err := k.p.Prices()
+	if err != nil {
+		return 0, errors.Wrap(err, "fetching prices failed")
+	}
+	open, err := k.p.OpenOrdersAll()
+	if err != nil {
+		return 0, errors.Wrap(err, "fetching open orders failed")
+	}
+	positions, err := k.p.MarginPositionAll()
+	if err != nil {
+		return 0, errors.Wrap(err, "fetching margin positions failed")
+	}
+	exposure := 0.0
+	add := func(amount float64, currency string) {
+		// anything without a price is valued at zero, as it is by Portfolio
+		if v, ok := prices.Convert(amount, currency, k.config.Currency); ok {
+			exposure += math.Abs(v)
+		}
+	}
+	for pair, orders := range open {
+		base, _ := splitPair(pair)
+		for _, o := range orders {
+			add(o.Total, base)
+		}
+	}
+	for pair, pos := range positions {
+		base, _ := splitPair(pair)
+		if pos.Type == "long" || pos.Type == "short" {
+			add(pos.Total, base)
+		}
+	}
+	return exposure, nil
+}
+
+// Trip halts order placement and cancels every open order and loan offer, closing margin positions if configured.
+// It does nothing if already tripped.
+func (k *KillSwitch) Trip(reason string) {
+	if reason == "" {
+		reason = "tripped by hand"
+	}
+	k.mutex.Lock()
+	if k.tripped {
+		k.mutex.Unlock()
+		return
+	}
+	k.tripped = true
+	k.mutex.Unlock()
+	// halt first so nothing new is placed while cancelling
+	k.p.halt(reason)
+
+	trip := KillSwitchTrip{Reason: reason, TS: time.Now()}
+	report, err := k.p.CancelAll(context.Background())
+	if err != nil {
+		trip.Errors = append(trip.Errors, err)
+	}
+	trip.Orders = report
+	offers, err := k.p.OpenLoanOffers()
+	if err != nil {
+		trip.Errors = append(trip.Errors, errors.Wrap(err, "fetching loan offers failed"))
+	}
+	for _, currency := range offers {
+		for _, o := range currency {
+			ok, err := k.p.CancelLoanOffer(o.ID)
+			if err == nil && !ok {
+				err = errors.New("offer was not cancelled")
+			}
+			if err != nil {
+				trip.Errors = append(trip.Errors, errors.Wrapf(err, "cancelling loan offer %d failed", o.ID))
+				continue
+			}
+			trip.LoanOffers++
+		}
+	}
+	if k.config.CloseMargin {
+		k.closeMargin(&trip)
+	}
+	k.p.Emit("killswitch-tripped", trip)
+}
+
+func (k *KillSwitch) closeMargin(trip *KillSwitchTrip) {
+	positions, err := k.p.MarginPositionAll()
+	if err != nil {
+		trip.Errors = append(trip.Errors, errors.Wrap(err, "fetching margin positions failed"))
+		return
+	}
+	for pair, pos := range positions {
+		if pos.Type != "long" && pos.Type != "short" {
+			continue
+		}
+		ok, err := k.p.CloseMarginPosition(pair)
+		if err == nil && !ok {
+			err = errors.New("position was not closed")
+		}
+		if err != nil {
+			trip.Errors = append(trip.Errors, errors.Wrap(err, "closing "+pair+" failed"))
+			continue
+		}
+		trip.MarginsClosed = append(trip.MarginsClosed, pair)
+	}
+}
@@ -0,0 +1,93 @@
+package poloniex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+func ExampleKillSwitch() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	btc := "10"
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			if req.Command == "returnCompleteBalances" {
+				return &Response{Status: 200, Body: `{"BTC":{"available":"` + btc + `","onOrders":"0"}}`}, nil
+			}
+			return next(req)
+		}
+	}, answer(map[string]string{
+		"returnTicker":                   `{"USDT_BTC":{"last":"10000"}}`,
+		"returnAvailableAccountBalances": `{}`,
+		"returnMarginAccountSummary":     `{}`,
+		"returnActiveLoans":              `{}`,
+		"returnOpenOrders":               `{"USDT_BTC":[{"orderNumber":"5","type":"buy","rate":"9000","amount":"1","total":"9000"}]}`,
+		"cancelOrder":                    `{"success":1}`,
+		"returnOpenLoanOffers":           `{"BTC":[{"id":7,"rate":"0.0001","amount":"1"}]}`,
+		"cancelLoanOffer":                `{"success":0}`,
+		"buy":                            `{"orderNumber":"6","resultingTrades":[]}`,
+	}))
+	p.On("killswitch-tripped", func(t KillSwitchTrip) {
+		fmt.Println("tripped:", t.Reason)
+		fmt.Println("cancelled", t.Orders.Cancelled, "orders and", t.LoanOffers, "loan offers")
+		for _, err := range t.Errors {
+			fmt.Println(err)
+		}
+	})
+
+	// the first check sets the baseline, the second sees 2 BTC lost
+	k := NewKillSwitch(p, KillSwitchConfig{MaxLoss: 1})
+	k.Check()
+	btc = "8"
+	k.Check()
+	_, err := p.Buy("USDT_BTC", 9000, 1)
+	fmt.Println(err)
+	k.Rearm()
+	_, err = p.Buy("USDT_BTC", 9000, 1)
+	fmt.Println(err)
+
+	// heartbeats keep the switch armed, it trips once they stop
+	h := NewKillSwitch(p, KillSwitchConfig{Timeout: 100 * time.Millisecond})
+	time.Sleep(60 * time.Millisecond)
+	h.Heartbeat()
+	time.Sleep(60 * time.Millisecond)
+	h.Check()
+	fmt.Println(h.Tripped())
+	time.Sleep(60 * time.Millisecond)
+	h.Check()
+	fmt.Println(h.Tripped())
+	// Output:
+	// tripped: loss of 2.00000000 BTC breaches the limit
+	// cancelled 1 orders and 0 loan offers
+	// cancelling loan offer 7 failed: offer was not cancelled
+	// loss of 2.00000000 BTC breaches the limit: trading is halted
+	// <nil>
+	// false
+	// tripped: no heartbeat for 0s
+	// cancelled 1 orders and 0 loan offers
+	// cancelling loan offer 7 failed: offer was not cancelled
+	// true
+}
+
+func ExampleKillSwitch_Trip() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(5, 1)
+	p.DryRun(ioutil.Discard)
+	k := NewKillSwitch(p, KillSwitchConfig{})
+	_, err := p.Buy("USDT_BTC", 9000, 1)
+	fmt.Println(err)
+
+	// this order waits for the rate limiter, and is stopped by the trip while it waits
+	done := make(chan error)
+	go func() {
+		_, err := p.Buy("USDT_BTC", 9000, 1)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	k.Trip("")
+	fmt.Println(<-done)
+	// Output:
+	// dry run, request not sent
+	// tripped by hand: trading is halted
+}
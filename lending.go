@@ -0,0 +1,271 @@
+package poloniex
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// LendingConfig controls how a LendingManager offers idle lending balances
+	LendingConfig struct {
+		// Currencies to lend, defaults to every currency with a lending balance
+		Currencies []string
+		// MinRate is the lowest daily rate (as a fraction, 0.0001 is 0.01%) an offer will be placed at
+		MinRate float64
+		// MinAmount is the smallest offer placed, defaults to 0.01
+		MinAmount float64
+		// Spreads is the number of offers the available balance is split into, defaults to 1
+		Spreads int
+		// DepthBottom and DepthTop are how far into the offer ladder, in units of the currency,
+		// the lowest and highest offers are priced. Both zero undercuts nobody and matches the lowest offer.
+		DepthBottom float64
+		DepthTop    float64
+		// Tiers pick the loan duration from the rate, the tier with the highest rate the offer meets is used, defaults to 2 days
+		Tiers []LendingTier
+		// AutoRenew sets the auto renew flag on placed offers
+		AutoRenew bool
+		// StaleAfter cancels offers which have not been taken for this long, zero never cancels
+		StaleAfter time.Duration
+		// Interval is the time between cycles when using Run, defaults to a minute
+		Interval time.Duration
+	}
+
+	// LendingTier maps a minimum daily rate to a loan duration in days
+	LendingTier struct {
+		Rate float64
+		Days int
+	}
+
+	// LendingManager keeps idle lending balances offered on the lending market
+	LendingManager struct {
+		p       *Poloniex
+		config  LendingConfig
+		started time.Time
+	}
+
+	// LendingReport summarises a single lending cycle
+	LendingReport struct {
+		Time       time.Time
+		Currencies []LendingCurrencyReport
+	}
+
+	// LendingCurrencyReport summarises the lending of a single currency
+	LendingCurrencyReport struct {
+		Currency  string
+		Available float64
+		Offered   float64
+		Lent      float64
+		// Utilisation is the fraction of the lending balance which is lent out
+		Utilisation float64
+		// AverageRate is the average daily rate of active loans, weighted by amount
+		AverageRate float64
+		// Earned is the interest earned, after fees, since the manager was created
+		Earned    float64
+		Placed    int
+		Cancelled int
+		// Err is why the currency could not be fully offered, the other currencies are still cycled
+		Err error
+	}
+)
+
+// NewLendingManager creates a lending manager for the client
+func NewLendingManager(p *Poloniex, config LendingConfig) *LendingManager {
+	if config.MinAmount <= 0 {
+		config.MinAmount = 0.01
+	}
+	if config.Spreads <= 0 {
+		config.Spreads = 1
+	}
+	if len(config.Tiers) == 0 {
+		config.Tiers = []LendingTier{{Rate: 0, Days: 2}}
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	return &LendingManager{p: p, config: config, started: time.Now()}
+}
+
+// Run cycles every Interval until ctx is done,
+// each report is emitted as a "lending-report" event and each failure, of a cycle or of a currency, as a "lending-error" event
+func (m *LendingManager) Run(ctx context.Context) error {
+	t := time.NewTicker(m.config.Interval)
+	defer t.Stop()
+	for {
+		report, err := m.Cycle()
+		if err != nil {
+			m.p.Emit("lending-error", err)
+		} else {
+			for _, r := range report.Currencies {
+				if r.Err != nil {
+					m.p.Emit("lending-error", r.Err)
+				}
+			}
+			m.p.Emit("lending-report", report)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Cycle cancels stale offers, offers all available lending balances and reports on the result.
+// A currency which fails has the error in its report, err is only for failing to read the account.
+func (m *LendingManager) Cycle() (report LendingReport, err error) {
+	report.Time = time.Now()
+	open, err := m.p.OpenLoanOffers()
+	if err != nil {
+		return report, errors.Wrap(err, "fetching open loan offers failed")
+	}
+	balances, err := m.p.AvailableAccountBalances()
+	if err != nil {
+		return report, errors.Wrap(err, "fetching account balances failed")
+	}
+	loans, err := m.p.ActiveLoans()
+	if err != nil {
+		return report, errors.Wrap(err, "fetching active loans failed")
+	}
+	history, err := m.p.LendingHistory(m.started.Unix(), report.Time.Unix(), 0)
+	if err != nil {
+		return report, errors.Wrap(err, "fetching lending history failed")
+	}
+
+	currencies := m.config.Currencies
+	if len(currencies) == 0 {
+		for k := range balances.Lending {
+			currencies = append(currencies, k)
+		}
+		sort.Strings(currencies)
+	}
+	for _, c := range currencies {
+		r := LendingCurrencyReport{Currency: c, Available: balances.Lending[c]}
+		for _, v := range open[c] {
+			if m.stale(v, report.Time) {
+				if ok, err := m.p.CancelLoanOffer(v.ID); err == nil && ok {
+					r.Cancelled++
+					r.Available += v.Amount
+					continue
+				}
+			}
+			r.Offered += v.Amount
+		}
+		if r.Available >= m.config.MinAmount {
+			placed, offered, err := m.offer(c, r.Available)
+			r.Placed, r.Offered, r.Available, r.Err = placed, r.Offered+offered, r.Available-offered, err
+		}
+		weighted := 0.0
+		for _, v := range loans.Provided {
+			if v.Currency == c {
+				r.Lent += v.Amount
+				weighted += v.Amount * v.Rate
+			}
+		}
+		if r.Lent > 0 {
+			r.AverageRate = weighted / r.Lent
+		}
+		if total := r.Available + r.Offered + r.Lent; total > 0 {
+			r.Utilisation = r.Lent / total
+		}
+		for _, v := range history {
+			if v.Currency == c {
+				r.Earned += v.Earned
+			}
+		}
+		report.Currencies = append(report.Currencies, r)
+	}
+	return
+}
+
+// stale reports whether an open offer should be cancelled
+func (m *LendingManager) stale(o OpenLoanOffer, now time.Time) bool {
+	if o.Rate < m.config.MinRate {
+		return true
+	}
+	if m.config.StaleAfter <= 0 {
+		return false
+	}
+	placed, err := parseTime(o.Date)
+	return err == nil && now.Sub(placed) > m.config.StaleAfter
+}
+
+// offer splits amount across the configured spreads, returning the number of offers placed and the amount offered
+func (m *LendingManager) offer(currency string, amount float64) (placed int, offered float64, err error) {
+	lo, err := m.p.LoanOrders(currency)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "fetching loan orders for "+currency+" failed")
+	}
+	rates := m.rates(lo.Offers)
+	if len(rates) == 0 {
+		return
+	}
+	each := floorAmount(amount / float64(len(rates)))
+	if each < m.config.MinAmount {
+		// not enough to spread, offer it all at the lowest rate
+		rates, each = rates[:1], amount
+	}
+	for i, rate := range rates {
+		if i == len(rates)-1 {
+			// the last offer takes what rounding each down left over
+			each = floorAmount(amount - each*float64(len(rates)-1))
+		}
+		// LoanOffer takes the rate as a percentage
+		res, err := m.p.LoanOffer(currency, each, m.duration(rate), m.config.AutoRenew, rate*100.0)
+		if err != nil {
+			return placed, offered, errors.Wrap(err, "placing loan offer for "+currency+" failed")
+		}
+		if res.Success == 1 {
+			placed++
+			offered += each
+		}
+	}
+	return
+}
+
+// rates picks the offer rates from the ladder of offers, never going below MinRate
+func (m *LendingManager) rates(ladder []LoanOrder) []float64 {
+	if len(ladder) == 0 {
+		if m.config.MinRate > 0 {
+			return []float64{m.config.MinRate}
+		}
+		return nil
+	}
+	offers := append([]LoanOrder{}, ladder...)
+	sort.Slice(offers, func(i, j int) bool { return offers[i].Rate < offers[j].Rate })
+	atDepth := func(depth float64) float64 {
+		cumulative := 0.0
+		for _, v := range offers {
+			cumulative += v.Amount
+			if cumulative >= depth {
+				return v.Rate
+			}
+		}
+		return offers[len(offers)-1].Rate
+	}
+	n := m.config.Spreads
+	rates := make([]float64, 0, n)
+	for i := 0; i < n; i++ {
+		depth := m.config.DepthBottom
+		if n > 1 {
+			depth += (m.config.DepthTop - m.config.DepthBottom) * float64(i) / float64(n-1)
+		}
+		rates = append(rates, math.Max(atDepth(depth), m.config.MinRate))
+	}
+	return rates
+}
+
+// duration picks the loan duration in days for a rate
+func (m *LendingManager) duration(rate float64) int {
+	days := 2
+	best := -1.0
+	for _, t := range m.config.Tiers {
+		if rate >= t.Rate && t.Rate > best {
+			best, days = t.Rate, t.Days
+		}
+	}
+	return days
+}
@@ -0,0 +1,47 @@
+package poloniex
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleLendingManager() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			switch {
+			case req.Command == "returnLoanOrders" && req.Params.Get("currency") == "ETH":
+				return nil, fmt.Errorf("service unavailable")
+			case req.Command == "createLoanOffer":
+				fmt.Println("offer", req.Params.Get("currency"), req.Params.Get("amount"), req.Params.Get("duration"))
+			}
+			return next(req)
+		}
+	}, answer(map[string]string{
+		"returnOpenLoanOffers":           `{}`,
+		"returnAvailableAccountBalances": `{"lending":{"BTC":"1","ETH":"5"}}`,
+		"returnActiveLoans":              `{}`,
+		"returnLendingHistory":           `[]`,
+		"returnLoanOrders":               `{"offers":[{"rate":"0.0001","amount":"10"},{"rate":"0.0002","amount":"10"},{"rate":"0.0003","amount":"10"}],"demands":[]}`,
+		"createLoanOffer":                `{"success":1,"orderID":1}`,
+	}))
+	// three offers from the bottom of the ladder to 30 deep, those at 0.02% or more are for 30 days
+	m := NewLendingManager(p, LendingConfig{
+		Spreads: 3, DepthTop: 30,
+		Tiers: []LendingTier{{Rate: 0, Days: 2}, {Rate: 0.0002, Days: 30}},
+	})
+	report, err := m.Cycle()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, r := range report.Currencies {
+		fmt.Printf("%s placed %d offered %.8f available %.8f %v\n", r.Currency, r.Placed, r.Offered, r.Available, r.Err)
+	}
+	// Output:
+	// offer BTC 0.33333333 2
+	// offer BTC 0.33333333 30
+	// offer BTC 0.33333334 30
+	// BTC placed 3 offered 1.00000000 available 0.00000000 <nil>
+	// ETH placed 0 offered 0.00000000 available 5.00000000 fetching loan orders for ETH failed: service unavailable
+}
@@ -0,0 +1,145 @@
+package poloniex
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LiveBook is an order book kept up to date from the websocket "<pair>-modify" and "<pair>-remove" events.
+// It is seeded from the REST API, so holds the top 40 levels of each side plus any levels changed since.
+type LiveBook struct {
+	pair    string
+	mutex   sync.RWMutex
+	bids    map[float64]float64
+	asks    map[float64]float64
+	updated time.Time
+}
+
+// LiveBook returns the live order book of a pair, creating and seeding it on first use.
+// There is one live book per pair per client, the pair must be subscribed to for it to stay current.
+func (p *Poloniex) LiveBook(pair string) (*LiveBook, error) {
+	p.booksMutex.Lock()
+	defer p.booksMutex.Unlock()
+	if b, ok := p.books[pair]; ok {
+		return b, nil
+	}
+	b := &LiveBook{pair: pair, bids: map[float64]float64{}, asks: map[float64]float64{}}
+	// listen before seeding so nothing is missed in between, the seed only replaces levels it holds
+	p.On(pair+"-modify", b.update).On(pair+"-remove", b.update)
+	ob, err := p.OrderBook(pair)
+	if err != nil {
+		p.Off(pair+"-modify", b.update).Off(pair+"-remove", b.update)
+		return nil, errors.Wrap(err, "seeding live book failed")
+	}
+	b.seed(ob)
+	p.books[pair] = b
+	return b, nil
+}
+
+// seed loads the levels of a REST order book
+func (b *LiveBook) seed(ob OrderBook) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, o := range ob.Bids {
+		b.bids[o.Rate] = o.Amount
+	}
+	for _, o := range ob.Asks {
+		b.asks[o.Rate] = o.Amount
+	}
+	b.updated = time.Now()
+}
+
+func (b *LiveBook) update(m WSOrderbook) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	side := b.asks
+	if m.Type == "bid" {
+		side = b.bids
+	}
+	if m.Event == "remove" || m.Amount == 0 {
+		delete(side, m.Rate)
+	} else {
+		side[m.Rate] = m.Amount
+	}
+	b.updated = m.TS
+	if m.TS.IsZero() {
+		b.updated = time.Now()
+	}
+	// a crossed book means a removal was missed, the side opposite the update is stale
+	for bid, ask := b.best(); bid > 0 && ask > 0 && bid >= ask; bid, ask = b.best() {
+		if m.Type == "bid" {
+			delete(b.asks, ask)
+		} else {
+			delete(b.bids, bid)
+		}
+	}
+}
+
+// best returns the best bid and ask, zero when a side is empty, the mutex must be held
+func (b *LiveBook) best() (bid, ask float64) {
+	for r := range b.bids {
+		if r > bid {
+			bid = r
+		}
+	}
+	for r := range b.asks {
+		if ask == 0 || r < ask {
+			ask = r
+		}
+	}
+	return
+}
+
+// Pair returns the pair of the book
+func (b *LiveBook) Pair() string {
+	return b.pair
+}
+
+// Best returns the best bid and ask, ok is false if either side is empty
+func (b *LiveBook) Best() (bid, ask float64, ok bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	bid, ask = b.best()
+	return bid, ask, bid > 0 && ask > 0
+}
+
+// Bids returns up to depth bids, best first, zero depth returns them all
+func (b *LiveBook) Bids(depth int) []Order {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return levels(b.bids, depth, true)
+}
+
+// Asks returns up to depth asks, best first, zero depth returns them all
+func (b *LiveBook) Asks(depth int) []Order {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return levels(b.asks, depth, false)
+}
+
+// Updated returns the time of the last change to the book
+func (b *LiveBook) Updated() time.Time {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.updated
+}
+
+func levels(side map[float64]float64, depth int, descending bool) []Order {
+	orders := make([]Order, 0, len(side))
+	for r, a := range side {
+		orders = append(orders, Order{Rate: r, Amount: a})
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if descending {
+			return orders[i].Rate > orders[j].Rate
+		}
+		return orders[i].Rate < orders[j].Rate
+	})
+	if depth > 0 && len(orders) > depth {
+		orders = orders[:depth]
+	}
+	return orders
+}
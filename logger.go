@@ -0,0 +1,109 @@
+package poloniex
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log message
+type Level int
+
+// Log levels, with the same values as log/slog
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+type (
+	// Logger receives the client's log messages with their fields as alternating keys and values,
+	// such as "command", "pair", "latency" and "status". *slog.Logger satisfies it, so can be passed to WithLogger directly.
+	Logger interface {
+		Debug(msg string, args ...interface{})
+		Info(msg string, args ...interface{})
+		Warn(msg string, args ...interface{})
+		Error(msg string, args ...interface{})
+	}
+
+	// Option configures a client as it is created
+	Option func(*Poloniex)
+
+	// nopLogger discards everything, it is the default so the client is silent unless given a logger
+	nopLogger struct{}
+
+	// textLogger writes a line per message of at least its level, see NewTextLogger
+	textLogger struct {
+		mutex sync.Mutex
+		w     io.Writer
+		level Level
+	}
+)
+
+// WithLogger makes the client log to l, by default it logs nothing
+func WithLogger(l Logger) Option {
+	return func(p *Poloniex) {
+		if l == nil {
+			l = nopLogger{}
+		}
+		p.logger = l
+	}
+}
+
+// NewTextLogger creates a logger writing messages of at least level to w as "time level message key=value ..." lines,
+// for when log/slog is not available
+func NewTextLogger(w io.Writer, level Level) Logger {
+	return &textLogger{w: w, level: level}
+}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+func (l *textLogger) Debug(msg string, args ...interface{}) { l.log(LevelDebug, msg, args) }
+func (l *textLogger) Info(msg string, args ...interface{})  { l.log(LevelInfo, msg, args) }
+func (l *textLogger) Warn(msg string, args ...interface{})  { l.log(LevelWarn, msg, args) }
+func (l *textLogger) Error(msg string, args ...interface{}) { l.log(LevelError, msg, args) }
+
+func (l *textLogger) log(level Level, msg string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+	b := strings.Builder{}
+	b.WriteString(time.Now().Format(time.RFC3339))
+	b.WriteString(" " + level.String() + " " + msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			fmt.Fprintf(&b, " !BADKEY=%v", args[i])
+			break
+		}
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	b.WriteString("\n")
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	io.WriteString(l.w, b.String())
+}
+
+// String returns the name of the level as log/slog writes it
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+// pairOf returns the pair a request is for, if any
+func pairOf(params url.Values) string {
+	return params.Get("currencyPair")
+}
@@ -0,0 +1,25 @@
+package poloniex
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+func ExampleNewTextLogger() {
+	buf := &bytes.Buffer{}
+	p := NewREST("key", "secret", WithLogger(NewTextLogger(buf, LevelWarn)))
+	p.Use(answer(map[string]string{"returnTicker": `{"USDT_BTC":`}))
+	p.Ticker()
+
+	l := NewTextLogger(buf, LevelInfo)
+	l.Debug("not written")
+	l.Info("connected", "channel", "ticker", "odd")
+	// each line starts with the time, which changes from run to run
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		fmt.Println(line[strings.Index(line, " ")+1:])
+	}
+	// Output:
+	// WARN decoding response failed command=returnTicker error=unexpected end of JSON input
+	// INFO connected channel=ticker !BADKEY=odd
+}
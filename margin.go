@@ -0,0 +1,227 @@
+package poloniex
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MarginLevel is the severity of a margin risk
+type MarginLevel int
+
+const (
+	// MarginOK is a position within all thresholds
+	MarginOK MarginLevel = iota
+	// MarginWarning is a position past a warning threshold
+	MarginWarning
+	// MarginCritical is a position past a critical threshold
+	MarginCritical
+)
+
+type (
+	// MarginMonitorConfig sets the thresholds a MarginMonitor alerts at
+	MarginMonitorConfig struct {
+		// WarningDistance and CriticalDistance are distances to the liquidation price, as a fraction of the current price
+		WarningDistance  float64
+		CriticalDistance float64
+		// WarningMargin and CriticalMargin are levels of the account's CurrentMargin, lower is riskier
+		WarningMargin  float64
+		CriticalMargin float64
+		// Refresh is how often positions and the account summary are re-read, defaults to 30 seconds
+		Refresh time.Duration
+		// ClosePositions closes a position with CloseMarginPosition when it turns critical
+		ClosePositions bool
+		// TransferCurrency and TransferAmount move funds from the exchange to the margin account when a position turns critical
+		TransferCurrency string
+		TransferAmount   float64
+	}
+
+	// MarginRisk is the risk of a single open margin position
+	MarginRisk struct {
+		Pair             string
+		Type             string
+		Amount           float64
+		Price            float64
+		LiquidationPrice float64
+		// Distance is how far the price can move against the position before liquidation, as a fraction of Price
+		Distance float64
+		// MarginRatio is the account's CurrentMargin
+		MarginRatio float64
+		Level       MarginLevel
+		TS          time.Time
+	}
+
+	// MarginMonitor watches open margin positions against live tickers.
+	// Every evaluation is emitted as a "margin-risk" event, and a position rising to a new level
+	// is emitted as a "margin-warning" or "margin-critical" event, all carrying a MarginRisk.
+	// Failures while refreshing or deleveraging are emitted as "margin-error" events.
+	MarginMonitor struct {
+		p         *Poloniex
+		config    MarginMonitorConfig
+		mutex     sync.Mutex
+		positions map[string]MarginPosition
+		summary   MarginAccountSummary
+		levels    map[string]MarginLevel
+	}
+)
+
+// String returns the name of the level
+func (l MarginLevel) String() string {
+	switch l {
+	case MarginWarning:
+		return "warning"
+	case MarginCritical:
+		return "critical"
+	}
+	return "ok"
+}
+
+// NewMarginMonitor creates a margin monitor for the client, tickers must be subscribed to for live prices
+func NewMarginMonitor(p *Poloniex, config MarginMonitorConfig) *MarginMonitor {
+	if config.Refresh <= 0 {
+		config.Refresh = 30 * time.Second
+	}
+	return &MarginMonitor{p: p, config: config, positions: map[string]MarginPosition{}, levels: map[string]MarginLevel{}}
+}
+
+// Run refreshes positions every Refresh and evaluates them on every ticker until ctx is done
+func (m *MarginMonitor) Run(ctx context.Context) error {
+	remove := m.p.listen("ticker", m.onTicker)
+	defer remove()
+	t := time.NewTicker(m.config.Refresh)
+	defer t.Stop()
+	for {
+		if err := m.Refresh(); err != nil {
+			m.p.Emit("margin-error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Refresh re-reads the open positions and the account summary
+func (m *MarginMonitor) Refresh() error {
+	positions, err := m.p.MarginPositionAll()
+	if err != nil {
+		return errors.Wrap(err, "fetching margin positions failed")
+	}
+	summary, err := m.p.MarginAccountSummary()
+	if err != nil {
+		return errors.Wrap(err, "fetching margin account summary failed")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.positions = map[string]MarginPosition{}
+	for k, v := range positions {
+		if v.Type == "long" || v.Type == "short" {
+			m.positions[k] = v
+		}
+	}
+	for k := range m.levels {
+		if _, ok := m.positions[k]; !ok {
+			delete(m.levels, k)
+		}
+	}
+	m.summary = summary
+	return nil
+}
+
+// Risks evaluates every open position at the prices given, positions without a price are left out
+func (m *MarginMonitor) Risks(prices *Prices) []MarginRisk {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	risks := []MarginRisk{}
+	for pair, pos := range m.positions {
+		base, quote := splitPair(pair)
+		if price, ok := prices.Rate(quote, base); ok {
+			risks = append(risks, m.evaluate(pair, pos, price))
+		}
+	}
+	return risks
+}
+
+func (m *MarginMonitor) onTicker(t WSTicker) {
+	m.mutex.Lock()
+	pos, ok := m.positions[t.Pair]
+	if !ok {
+		m.mutex.Unlock()
+		return
+	}
+	// a long is closed by selling into the bid, a short by buying from the ask
+	price := t.Bid
+	if pos.Type == "short" {
+		price = t.Ask
+	}
+	risk := m.evaluate(t.Pair, pos, price)
+	previous := m.levels[t.Pair]
+	m.levels[t.Pair] = risk.Level
+	m.mutex.Unlock()
+
+	m.p.Emit("margin-risk", risk)
+	if risk.Level <= previous {
+		return
+	}
+	m.p.Emit("margin-"+risk.Level.String(), risk)
+	if risk.Level == MarginCritical {
+		// the REST calls would hold up the websocket while they are made
+		go m.deleverage(risk)
+	}
+}
+
+// evaluate computes the risk of a position at price
+func (m *MarginMonitor) evaluate(pair string, pos MarginPosition, price float64) MarginRisk {
+	r := MarginRisk{
+		Pair: pair, Type: pos.Type, Amount: pos.Amount, Price: price,
+		LiquidationPrice: pos.LiquidationPrice, MarginRatio: m.summary.CurrentMargin, TS: time.Now(),
+	}
+	if price > 0 && pos.LiquidationPrice > 0 {
+		r.Distance = (price - pos.LiquidationPrice) / price
+		if pos.Type == "short" {
+			r.Distance = (pos.LiquidationPrice - price) / price
+		}
+	}
+	level := func(value, warning, critical float64) MarginLevel {
+		switch {
+		case critical > 0 && value <= critical:
+			return MarginCritical
+		case warning > 0 && value <= warning:
+			return MarginWarning
+		}
+		return MarginOK
+	}
+	if pos.LiquidationPrice > 0 {
+		r.Level = level(r.Distance, m.config.WarningDistance, m.config.CriticalDistance)
+	}
+	// no summary has been read yet if the ratio is zero
+	if l := level(r.MarginRatio, m.config.WarningMargin, m.config.CriticalMargin); r.MarginRatio > 0 && l > r.Level {
+		r.Level = l
+	}
+	return r
+}
+
+// deleverage applies the configured automatic actions to a critical position
+func (m *MarginMonitor) deleverage(r MarginRisk) {
+	if m.config.TransferCurrency != "" && m.config.TransferAmount > 0 {
+		tb, err := m.p.TransferBalance(m.config.TransferCurrency, m.config.TransferAmount, "exchange", "margin")
+		if err == nil && tb.Success != 1 {
+			err = errors.New(tb.Message)
+		}
+		if err != nil {
+			m.p.Emit("margin-error", errors.Wrap(err, "transferring to margin account failed"))
+		}
+	}
+	if m.config.ClosePositions {
+		ok, err := m.p.CloseMarginPosition(r.Pair)
+		if err == nil && !ok {
+			err = errors.New("position was not closed")
+		}
+		if err != nil {
+			m.p.Emit("margin-error", errors.Wrap(err, "closing "+r.Pair+" failed"))
+		}
+	}
+}
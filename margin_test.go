@@ -0,0 +1,44 @@
+package poloniex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func ExampleMarginMonitor() {
+	p := NewREST("key", "secret")
+	closed := make(chan string, 1)
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			switch req.Command {
+			case "getMarginPosition":
+				return &Response{Status: 200, Body: `{"BTC_ETH":{"amount":"10","liquidationPrice":"0.019","type":"long"}}`}, nil
+			case "returnMarginAccountSummary":
+				return &Response{Status: 200, Body: `{"currentMargin":"0.9"}`}, nil
+			case "closeMarginPosition":
+				closed <- req.Params.Get("currencyPair")
+				return &Response{Status: 200, Body: `{"success":1}`}, nil
+			}
+			return nil, fmt.Errorf("unexpected %s", req.Command)
+		}
+	})
+	m := NewMarginMonitor(p, MarginMonitorConfig{CriticalDistance: 0.05, ClosePositions: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case pair := <-closed:
+			fmt.Println("closed", pair)
+			return
+		case <-tick.C:
+			// a bid 2.6% above the liquidation price is critical
+			p.Emit("ticker", WSTicker{Pair: "BTC_ETH", Bid: 0.0195, Ask: 0.0196})
+		}
+	}
+	// Output: closed BTC_ETH
+}
@@ -0,0 +1,160 @@
+package poloniex
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// MarketOrder is the result of a market order
+	MarketOrder struct {
+		OrderNumber int64
+		// Rate is the limit rate the immediate-or-cancel order was placed at
+		Rate         float64
+		Amount       float64
+		Filled       float64
+		Total        float64
+		AveragePrice float64
+		// Fees are in the currency received, the traded currency for a buy and the pricing currency for a sell
+		Fees            float64
+		ResultingTrades []ResultingTrade
+	}
+)
+
+// MarketBuy buys amount as soon as possible, walking the order book to find the rate needed.
+// maxSlippage caps how far above the best ask the rate may go, as a fraction (0.01 is 1%).
+// The order is placed immediate-or-cancel so nothing is left on the book.
+func (p *Poloniex) MarketBuy(pair string, amount, maxSlippage float64) (mo MarketOrder, err error) {
+	ob, err := p.OrderBook(pair)
+	if err != nil {
+		return mo, errors.Wrap(err, "fetching order book failed")
+	}
+	rate, _, err := walkBook(ob.Asks, amount, 0, maxSlippage, true)
+	if err != nil {
+		return
+	}
+	buy, err := p.BuyImmediateOrCancel(pair, rate, amount)
+	if err != nil {
+		return
+	}
+	return marketOrder(buy, rate, amount, true), nil
+}
+
+// MarketBuyTotal spends up to total (in the currency the pair is priced in, e.g. 100 USDT on USDT_BTC) as soon as possible.
+// The rate needed is found against the current depth, maxSlippage is as for MarketBuy, and the amount is total at that rate
+// so no more than total is ever reserved. Any better priced fills leave some of total unspent.
+func (p *Poloniex) MarketBuyTotal(pair string, total, maxSlippage float64) (mo MarketOrder, err error) {
+	ob, err := p.OrderBook(pair)
+	if err != nil {
+		return mo, errors.Wrap(err, "fetching order book failed")
+	}
+	rate, _, err := walkBook(ob.Asks, 0, total, maxSlippage, true)
+	if err != nil {
+		return
+	}
+	amount := floorAmount(total / rate)
+	buy, err := p.BuyImmediateOrCancel(pair, rate, amount)
+	if err != nil {
+		return
+	}
+	return marketOrder(buy, rate, amount, true), nil
+}
+
+// MarketSell sells amount as soon as possible, walking the order book to find the rate needed.
+// maxSlippage caps how far below the best bid the rate may go, as a fraction (0.01 is 1%).
+// The order is placed immediate-or-cancel so nothing is left on the book.
+func (p *Poloniex) MarketSell(pair string, amount, maxSlippage float64) (mo MarketOrder, err error) {
+	ob, err := p.OrderBook(pair)
+	if err != nil {
+		return mo, errors.Wrap(err, "fetching order book failed")
+	}
+	rate, _, err := walkBook(ob.Bids, amount, 0, maxSlippage, false)
+	if err != nil {
+		return
+	}
+	sell, err := p.SellImmediateOrCancel(pair, rate, amount)
+	if err != nil {
+		return
+	}
+	return marketOrder(sell.Buy, rate, amount, false), nil
+}
+
+// MarketSellTotal sells enough to receive total (in the currency the pair is priced in) as soon as possible,
+// maxSlippage is as for MarketSell.
+func (p *Poloniex) MarketSellTotal(pair string, total, maxSlippage float64) (mo MarketOrder, err error) {
+	ob, err := p.OrderBook(pair)
+	if err != nil {
+		return mo, errors.Wrap(err, "fetching order book failed")
+	}
+	rate, amount, err := walkBook(ob.Bids, 0, total, maxSlippage, false)
+	if err != nil {
+		return
+	}
+	// rounded down, as %.8f could otherwise round it up past the balance
+	amount = floorAmount(amount)
+	sell, err := p.SellImmediateOrCancel(pair, rate, amount)
+	if err != nil {
+		return
+	}
+	return marketOrder(sell.Buy, rate, amount, false), nil
+}
+
+// walkBook finds the worst rate needed to fill either amount or total from one side of the book,
+// along with the amount that fills. It fails if the book is too thin within maxSlippage of the best price,
+// bear in mind OrderBook only returns the top 40 entries.
+func walkBook(side []Order, amount, total, maxSlippage float64, buy bool) (rate, filled float64, err error) {
+	if len(side) == 0 {
+		return 0, 0, errors.New("order book is empty")
+	}
+	if amount <= 0 && total <= 0 {
+		return 0, 0, errors.New("amount must be positive")
+	}
+	limit := side[0].Rate * (1 + maxSlippage)
+	if !buy {
+		limit = side[0].Rate * (1 - maxSlippage)
+	}
+	spent := 0.0
+	for _, o := range side {
+		if (buy && o.Rate > limit) || (!buy && o.Rate < limit) {
+			break
+		}
+		rate = o.Rate
+		take := o.Amount
+		if amount > 0 {
+			take = math.Min(take, amount-filled)
+		} else {
+			take = math.Min(take, (total-spent)/o.Rate)
+		}
+		filled += take
+		spent += take * o.Rate
+		if (amount > 0 && filled >= amount-filledEpsilon) || (amount <= 0 && spent >= total-filledEpsilon) {
+			return rate, filled, nil
+		}
+	}
+	return 0, 0, errors.Errorf("not enough depth within %.2f%% of the best price", maxSlippage*100)
+}
+
+// floorAmount rounds an amount down to the 8 decimal places the exchange takes
+func floorAmount(amount float64) float64 {
+	// the small nudge keeps amounts which are whole in 8 decimals from falling a unit short
+	return math.Floor(amount*1e8+1e-6) / 1e8
+}
+
+func marketOrder(b Buy, rate, amount float64, buy bool) (mo MarketOrder) {
+	mo = MarketOrder{OrderNumber: b.OrderNumber, Rate: rate, Amount: amount, ResultingTrades: b.ResultingTrades}
+	for _, t := range b.ResultingTrades {
+		mo.Filled += t.Amount
+		mo.Total += t.Total
+		// the fee is returned as a rate, and taken from the currency received
+		if buy {
+			mo.Fees += t.Amount * t.Fee
+		} else {
+			mo.Fees += t.Total * t.Fee
+		}
+	}
+	if mo.Filled > 0 {
+		mo.AveragePrice = mo.Total / mo.Filled
+	}
+	return
+}
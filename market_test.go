@@ -0,0 +1,54 @@
+package poloniex
+
+import (
+	"fmt"
+)
+
+func ExamplePoloniex_MarketBuyTotal() {
+	p := NewREST("key", "secret")
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			switch req.Command {
+			case "returnOrderBook":
+				return &Response{Status: 200, Body: `{"asks":[["7000.00000000",0.01],["7010.00000000",0.02],["7100.00000000",5]],"bids":[],"isFrozen":"0","seq":1}`}, nil
+			case "buy":
+				fmt.Println("buy", req.Params.Get("rate"), req.Params.Get("amount"), req.Params.Get("immediateOrCancel"))
+				return &Response{Status: 200, Body: `{"orderNumber":"1","resultingTrades":[]}`}, nil
+			}
+			return nil, fmt.Errorf("unexpected %s", req.Command)
+		}
+	})
+	// 300 USDT takes the first two asks and part of the third, so 300/7100 is bought at the third's rate
+	mo, err := p.MarketBuyTotal("USDT_BTC", 300, 0.02)
+	fmt.Println(mo.Rate, mo.Amount, err)
+
+	// the third ask is beyond 0.1% of the best, and the first two are not enough
+	_, err = p.MarketBuyTotal("USDT_BTC", 1000, 0.001)
+	fmt.Println(err)
+	// Output:
+	// buy 7100.00000000 0.04225352 1
+	// 7100 0.04225352 <nil>
+	// not enough depth within 0.10% of the best price
+}
+
+func ExamplePoloniex_MarketSellTotal() {
+	p := NewREST("key", "secret")
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			switch req.Command {
+			case "returnOrderBook":
+				return &Response{Status: 200, Body: `{"asks":[],"bids":[["7000.00000000",1]],"isFrozen":"0","seq":1}`}, nil
+			case "sell":
+				fmt.Println("sell", req.Params.Get("rate"), req.Params.Get("amount"), req.Params.Get("immediateOrCancel"))
+				return &Response{Status: 200, Body: `{"orderNumber":"1","resultingTrades":[]}`}, nil
+			}
+			return nil, fmt.Errorf("unexpected %s", req.Command)
+		}
+	})
+	// 130/7000 is 0.0185714285..., which is sold as 0.01857142 rather than rounded up
+	mo, err := p.MarketSellTotal("USDT_BTC", 130, 0.01)
+	fmt.Println(mo.Rate, mo.Amount, err)
+	// Output:
+	// sell 7000.00000000 0.01857142 1
+	// 7000 0.01857142 <nil>
+}
@@ -0,0 +1,288 @@
+package poloniex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The classes of error passed to Metrics.Error
+const (
+	// ErrorClassNetwork is a request which could not be sent or whose response could not be read
+	ErrorClassNetwork = "network"
+	// ErrorClassAPI is an error returned by the exchange
+	ErrorClassAPI = "api"
+	// ErrorClassNonce is a nonce rejected by the exchange, the request is retried
+	ErrorClassNonce = "nonce"
+	// ErrorClassDecode is a response which did not decode into the result
+	ErrorClassDecode = "decode"
+	// ErrorClassHalted is an order refused because trading is halted, see KillSwitch
+	ErrorClassHalted = "halted"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the latency histograms, the same as Prometheus' defaults
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type (
+	// Metrics receives measurements from the client, it must be safe for concurrent use.
+	// MetricsRegistry implements it, or it can be adapted to any metrics library.
+	Metrics interface {
+		// Request is called after every REST request sent, with the time to the response
+		Request(command string, latency time.Duration)
+		// Error is called for every failed REST request, class is one of the ErrorClass constants
+		Error(command, class string)
+		// RateLimitWait is called with the time every REST request waited for the rate limiter
+		RateLimitWait(wait time.Duration)
+		// WSMessage is called for every websocket message, channel is the pair or channel name
+		WSMessage(channel string)
+		// Reconnect is called when the websocket delivers messages again after losing its connection
+		Reconnect()
+		// SequenceGap is called when an order book channel skips sequence numbers, so the book may be stale
+		SequenceGap(channel string)
+		// QueueDepth is called with the number of events waiting for subscribers in a queue
+		QueueDepth(queue string, depth int)
+	}
+
+	// nopMetrics discards everything, it is the default
+	nopMetrics struct{}
+
+	// MetricsRegistry keeps the client's metrics in memory and writes them in the Prometheus text format,
+	// serve it on /metrics or copy the values into another library
+	MetricsRegistry struct {
+		mutex     sync.Mutex
+		buckets   []float64
+		requests  map[string]*histogram
+		errors    map[[2]string]int64
+		waits     *histogram
+		messages  map[string]int64
+		reconnect int64
+		gaps      map[string]int64
+		depths    map[string]int
+	}
+
+	// histogram counts observations into cumulative buckets
+	histogram struct {
+		counts []int64
+		count  int64
+		sum    float64
+	}
+)
+
+// WithMetrics makes the client report to m, by default nothing is measured
+func WithMetrics(m Metrics) Option {
+	return func(p *Poloniex) {
+		if m == nil {
+			m = nopMetrics{}
+		}
+		p.metrics = m
+	}
+}
+
+func (nopMetrics) Request(command string, latency time.Duration) {}
+func (nopMetrics) Error(command, class string)                   {}
+func (nopMetrics) RateLimitWait(wait time.Duration)              {}
+func (nopMetrics) WSMessage(channel string)                      {}
+func (nopMetrics) Reconnect()                                    {}
+func (nopMetrics) SequenceGap(channel string)                    {}
+func (nopMetrics) QueueDepth(queue string, depth int)            {}
+
+// NewMetricsRegistry creates a registry with latency histograms of the given buckets in seconds, DefaultBuckets if none
+func NewMetricsRegistry(buckets ...float64) *MetricsRegistry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &MetricsRegistry{
+		buckets:  buckets,
+		requests: map[string]*histogram{},
+		errors:   map[[2]string]int64{},
+		waits:    newHistogram(buckets),
+		messages: map[string]int64{},
+		gaps:     map[string]int64{},
+		depths:   map[string]int{},
+	}
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{counts: make([]int64, len(buckets))}
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	for i, b := range buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Request records a request and its latency
+func (r *MetricsRegistry) Request(command string, latency time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	h, ok := r.requests[command]
+	if !ok {
+		h = newHistogram(r.buckets)
+		r.requests[command] = h
+	}
+	h.observe(r.buckets, latency.Seconds())
+}
+
+// Error records a failed request
+func (r *MetricsRegistry) Error(command, class string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.errors[[2]string{command, class}]++
+}
+
+// RateLimitWait records the time a request waited for the rate limiter
+func (r *MetricsRegistry) RateLimitWait(wait time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.waits.observe(r.buckets, wait.Seconds())
+}
+
+// WSMessage records a websocket message
+func (r *MetricsRegistry) WSMessage(channel string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages[channel]++
+}
+
+// Reconnect records a websocket reconnection
+func (r *MetricsRegistry) Reconnect() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reconnect++
+}
+
+// SequenceGap records a gap in a channel's sequence numbers
+func (r *MetricsRegistry) SequenceGap(channel string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.gaps[channel]++
+}
+
+// QueueDepth records the current depth of a queue
+func (r *MetricsRegistry) QueueDepth(queue string, depth int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.depths[queue] = depth
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition format, with series sorted by label
+func (r *MetricsRegistry) WritePrometheus(w io.Writer) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	b := &strings.Builder{}
+
+	header(b, "poloniex_requests_total", "counter", "REST requests sent, by command.")
+	for _, command := range sortedKeys(r.requests) {
+		fmt.Fprintf(b, "poloniex_requests_total{command=%q} %d\n", command, r.requests[command].count)
+	}
+	header(b, "poloniex_request_duration_seconds", "histogram", "REST request latency, by command.")
+	for _, command := range sortedKeys(r.requests) {
+		r.writeHistogram(b, "poloniex_request_duration_seconds", fmt.Sprintf("command=%q", command), r.requests[command])
+	}
+
+	header(b, "poloniex_errors_total", "counter", "Failed REST requests, by command and class of error.")
+	keys := make([][2]string, 0, len(r.errors))
+	for k := range r.errors {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(b, "poloniex_errors_total{command=%q,class=%q} %d\n", k[0], k[1], r.errors[k])
+	}
+
+	header(b, "poloniex_ratelimit_wait_seconds", "histogram", "Time REST requests waited for the rate limiter.")
+	r.writeHistogram(b, "poloniex_ratelimit_wait_seconds", "", r.waits)
+
+	header(b, "poloniex_ws_messages_total", "counter", "Websocket messages received, by channel.")
+	for _, channel := range sortedKeys(r.messages) {
+		fmt.Fprintf(b, "poloniex_ws_messages_total{channel=%q} %d\n", channel, r.messages[channel])
+	}
+	header(b, "poloniex_ws_reconnects_total", "counter", "Websocket reconnections.")
+	fmt.Fprintf(b, "poloniex_ws_reconnects_total %d\n", r.reconnect)
+	header(b, "poloniex_ws_sequence_gaps_total", "counter", "Gaps in order book sequence numbers, by channel.")
+	for _, channel := range sortedKeys(r.gaps) {
+		fmt.Fprintf(b, "poloniex_ws_sequence_gaps_total{channel=%q} %d\n", channel, r.gaps[channel])
+	}
+
+	header(b, "poloniex_queue_depth", "gauge", "Events waiting for subscribers, by queue.")
+	for _, queue := range sortedKeys(r.depths) {
+		fmt.Fprintf(b, "poloniex_queue_depth{queue=%q} %d\n", queue, r.depths[queue])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the metrics for scraping
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WritePrometheus(w)
+}
+
+// writeHistogram writes the buckets, sum and count of a histogram, labels are any other labels of the series
+func (r *MetricsRegistry) writeHistogram(b *strings.Builder, name, labels string, h *histogram) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	for i, bound := range r.buckets {
+		fmt.Fprintf(b, "%s_bucket{%s%sle=%q} %d\n", name, labels, sep, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(b, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, labels, h.count)
+}
+
+func header(b *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sortedKeys returns the keys of a map with string keys, sorted
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch m := m.(type) {
+	case map[string]*histogram:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]int64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]int:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// wait waits for the rate limiter, reporting how long it took, it gives up when ctx is done
+func (p *Poloniex) wait(ctx context.Context) error {
+	start := time.Now()
+	err := p.rateLimiter().Wait(ctx)
+	p.metrics.RateLimitWait(time.Since(start))
+	return err
+}
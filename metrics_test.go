@@ -0,0 +1,71 @@
+package poloniex
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+func ExampleMetricsRegistry() {
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, map[string]string{"121": "USDT_BTC"})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	ts := time.Unix(1500000000, 0)
+	rec.Record(ts, []byte(`[121,1,[["o",1,"7000.00000000","0.50000000"]]]`))
+	rec.Record(ts, []byte(`[121,2,[["o",0,"7001.00000000","0.20000000"]]]`))
+	// sequence number 3 is missing
+	rec.Record(ts, []byte(`[121,4,[["t","1",0,"7001.00000000","0.10000000",1500000001]]]`))
+	rec.Record(ts, []byte(`[1010]`))
+	rec.Close()
+
+	rep, err := NewReplayer(buf)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer rep.Close()
+	metrics := NewMetricsRegistry(0.1, 1)
+	p := rep.Client(WithMetrics(metrics))
+	if err := p.Replay(context.Background(), rep, ReplayMaxSpeed); err != nil {
+		log.Fatalln(err)
+	}
+	metrics.Request("returnTicker", 50*time.Millisecond)
+	metrics.Error("buy", ErrorClassAPI)
+	metrics.WritePrometheus(os.Stdout)
+	// Output:
+	// # HELP poloniex_requests_total REST requests sent, by command.
+	// # TYPE poloniex_requests_total counter
+	// poloniex_requests_total{command="returnTicker"} 1
+	// # HELP poloniex_request_duration_seconds REST request latency, by command.
+	// # TYPE poloniex_request_duration_seconds histogram
+	// poloniex_request_duration_seconds_bucket{command="returnTicker",le="0.1"} 1
+	// poloniex_request_duration_seconds_bucket{command="returnTicker",le="1"} 1
+	// poloniex_request_duration_seconds_bucket{command="returnTicker",le="+Inf"} 1
+	// poloniex_request_duration_seconds_sum{command="returnTicker"} 0.05
+	// poloniex_request_duration_seconds_count{command="returnTicker"} 1
+	// # HELP poloniex_errors_total Failed REST requests, by command and class of error.
+	// # TYPE poloniex_errors_total counter
+	// poloniex_errors_total{command="buy",class="api"} 1
+	// # HELP poloniex_ratelimit_wait_seconds Time REST requests waited for the rate limiter.
+	// # TYPE poloniex_ratelimit_wait_seconds histogram
+	// poloniex_ratelimit_wait_seconds_bucket{le="0.1"} 0
+	// poloniex_ratelimit_wait_seconds_bucket{le="1"} 0
+	// poloniex_ratelimit_wait_seconds_bucket{le="+Inf"} 0
+	// poloniex_ratelimit_wait_seconds_sum 0
+	// poloniex_ratelimit_wait_seconds_count 0
+	// # HELP poloniex_ws_messages_total Websocket messages received, by channel.
+	// # TYPE poloniex_ws_messages_total counter
+	// poloniex_ws_messages_total{channel="USDT_BTC"} 3
+	// poloniex_ws_messages_total{channel="heartbeat"} 1
+	// # HELP poloniex_ws_reconnects_total Websocket reconnections.
+	// # TYPE poloniex_ws_reconnects_total counter
+	// poloniex_ws_reconnects_total 0
+	// # HELP poloniex_ws_sequence_gaps_total Gaps in order book sequence numbers, by channel.
+	// # TYPE poloniex_ws_sequence_gaps_total counter
+	// poloniex_ws_sequence_gaps_total{channel="USDT_BTC"} 1
+	// # HELP poloniex_queue_depth Events waiting for subscribers, by queue.
+	// # TYPE poloniex_queue_depth gauge
+}
@@ -0,0 +1,325 @@
+package poloniex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/franela/goreq"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Request is a REST request passing through the middleware chain
+	Request struct {
+		Context context.Context
+		Private bool
+		Command string
+		// Params holds the query of a public request or the body of a private one, including the command
+		Params url.Values
+		// Header holds the headers of a private request, set when it is signed
+		Header http.Header
+		// Result is what the response body is decoded into
+		Result interface{}
+		// Attempt counts from 1, private requests are sent again after the exchange rejects their nonce
+		Attempt int
+	}
+
+	// Response is the raw response to a REST request
+	Response struct {
+		Status int
+		// Body is empty when a private request has no real data to return
+		Body string
+	}
+
+	// Handler sends a request on, to the next middleware or to the exchange
+	Handler func(req *Request) (*Response, error)
+
+	// Middleware wraps a handler, it may change the request, the response or the error, or answer without calling next
+	Middleware func(next Handler) Handler
+)
+
+// Use adds middleware around both REST paths, req.Private tells them apart. It should be called before the client is in use.
+//
+// The built in middleware runs in this order, with the added middleware in the order given in place of the dots:
+//
+//	public:  trace, decode, ..., rate limit, dry run, send
+//	private: trace, decode, halt check, ..., nonce retry, rate limit, halt check, nonce, sign, dry run, parse error, send
+//
+// So added middleware sees the request before its nonce and signature, and the raw body before it is decoded,
+// which makes it the place for caching, auditing, fault injection and the like. Orders refused while trading
+// is halted never reach it. It may answer with a nil response, which leaves the result as it was.
+func (p *Poloniex) Use(m ...Middleware) {
+	p.middleware = append(p.middleware, m...)
+}
+
+// chain wraps send in middleware, the first outermost
+func chain(send Handler, middleware ...Middleware) Handler {
+	h := send
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// publicChain is the handler public requests go through
+func (p *Poloniex) publicChain() Handler {
+	m := []Middleware{p.traceMiddleware, p.decodeMiddleware}
+	m = append(m, p.userMiddleware()...)
+	m = append(m, p.rateLimitMiddleware, p.dryRunMiddleware)
+	return chain(p.sendPublic, m...)
+}
+
+// privateChain is the handler private requests go through
+func (p *Poloniex) privateChain() Handler {
+	m := []Middleware{p.traceMiddleware, p.decodeMiddleware, p.haltMiddleware}
+	m = append(m, p.userMiddleware()...)
+	// the halt is checked again after waiting for the rate limiter, the client may have been halted meanwhile
+	m = append(m, p.nonceRetryMiddleware, p.rateLimitMiddleware, p.haltMiddleware, p.nonceMiddleware,
+		p.signMiddleware, p.dryRunMiddleware, p.parseErrorMiddleware)
+	return chain(p.sendPrivate, m...)
+}
+
+// userMiddleware is the middleware added by Use, an account client's comes after its public client's
+func (p *Poloniex) userMiddleware() []Middleware {
+	if p.shared == nil {
+		return p.middleware
+	}
+	return append(append([]Middleware{}, p.shared.userMiddleware()...), p.middleware...)
+}
+
+// rateLimiter is the limiter requests wait for, an account client waits for its public client's unless given its own
+func (p *Poloniex) rateLimiter() *RateLimiter {
+	if p.limiter == nil && p.shared != nil {
+		return p.shared.rateLimiter()
+	}
+	return p.limiter
+}
+
+// traceMiddleware wraps the whole request in a span
+func (p *Poloniex) traceMiddleware(next Handler) Handler {
+	return func(req *Request) (res *Response, err error) {
+		name := "poloniex.public"
+		if req.Private {
+			name = "poloniex.private"
+		}
+		ctx, span := p.tracer.Start(req.Context, name)
+		span.SetAttribute("command", req.Command)
+		if pair := pairOf(req.Params); pair != "" {
+			span.SetAttribute("pair", pair)
+		}
+		defer func() {
+			if req.Attempt > 0 {
+				span.SetAttribute("attempts", req.Attempt)
+			} else if res != nil {
+				span.SetAttribute("http.status_code", res.Status)
+			}
+			endSpan(span, err)
+		}()
+		req.Context = ctx
+		return next(req)
+	}
+}
+
+// decodeMiddleware unmarshals the response body into the request's result
+func (p *Poloniex) decodeMiddleware(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		res, err := next(req)
+		if err != nil || res == nil || res.Body == "" {
+			return res, err
+		}
+		if err := json.Unmarshal([]byte(res.Body), req.Result); err != nil {
+			p.metrics.Error(req.Command, ErrorClassDecode)
+			p.logger.Warn("decoding response failed", "command", req.Command, "error", err)
+			return res, err
+		}
+		return res, nil
+	}
+}
+
+// haltMiddleware refuses to place orders while trading is halted, see KillSwitch
+func (p *Poloniex) haltMiddleware(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if err := p.checkHalted(req.Command); err != nil {
+			p.metrics.Error(req.Command, ErrorClassHalted)
+			return nil, err
+		}
+		return next(req)
+	}
+}
+
+// nonceRetryMiddleware sends a request again when its nonce is rejected, concurrent requests can reach
+// the server out of nonce order
+func (p *Poloniex) nonceRetryMiddleware(next Handler) Handler {
+	return func(req *Request) (res *Response, err error) {
+		for req.Attempt = 1; ; req.Attempt++ {
+			res, err = p.attempt(next, req)
+			if err == nil || req.Attempt > nonceRetries || !p.skipNonce(err) {
+				return
+			}
+			p.logger.Info("nonce rejected, retrying", "command", req.Command, "attempt", req.Attempt)
+		}
+	}
+}
+
+// attempt sends one attempt at a private request in a span of its own
+func (p *Poloniex) attempt(next Handler, req *Request) (res *Response, err error) {
+	_, span := p.tracer.Start(req.Context, "poloniex.private.request")
+	span.SetAttribute("command", req.Command)
+	span.SetAttribute("attempt", req.Attempt)
+	defer func() {
+		if res != nil && res.Status != 0 {
+			span.SetAttribute("http.status_code", res.Status)
+		}
+		endSpan(span, err)
+	}()
+	return next(req)
+}
+
+// rateLimitMiddleware waits for the client's rate limiter, or for the request's context to be done
+func (p *Poloniex) rateLimitMiddleware(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if err := p.wait(req.Context); err != nil {
+			return nil, errors.Wrap(err, "waiting for the rate limiter failed")
+		}
+		return next(req)
+	}
+}
+
+// nonceMiddleware takes a fresh nonce for every attempt
+func (p *Poloniex) nonceMiddleware(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		nonce, err := p.getNonce()
+		if err != nil {
+			return nil, err
+		}
+		req.Params.Set("nonce", nonce)
+		return next(req)
+	}
+}
+
+// signMiddleware sets the key and the signature of the body
+func (p *Poloniex) signMiddleware(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		body := req.Params.Encode()
+		c := p.getCredentials()
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		req.Header.Set("Key", c.Key)
+		req.Header.Set("Sign", sign(c.Secret, body))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return next(req)
+	}
+}
+
+// dryRunMiddleware writes the request out instead of sending it, see DryRun
+func (p *Poloniex) dryRunMiddleware(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		if p.dryRun == nil {
+			return next(req)
+		}
+		if !req.Private {
+			fmt.Fprintf(p.dryRun, "GET %s?%s\n", PUBLICURI, req.Params.Encode())
+			return nil, ErrDryRun
+		}
+		fmt.Fprintf(p.dryRun, "POST %s\nKey: %s\nSign: %s\nContent-Type: application/x-www-form-urlencoded\n\n%s\n",
+			PRIVATEURI, redactKey(req.Header.Get("Key")), req.Header.Get("Sign"), req.Params.Encode())
+		return nil, ErrDryRun
+	}
+}
+
+// parseErrorMiddleware turns an error returned by the exchange into an error, and empties the body
+// when there is no real data
+func (p *Poloniex) parseErrorMiddleware(next Handler) Handler {
+	return func(req *Request) (*Response, error) {
+		res, err := next(req)
+		if err != nil {
+			return res, err
+		}
+		if strings.HasPrefix(res.Body, "[") {
+			// TODO: fix this shit
+			//  poloniex only ever returns an array type when there is no real data
+			//  e.g. no data in a time range
+			//  if this ever changes then this breaks badly
+			res.Body = ""
+			return res, nil
+		}
+
+		//  do we have an error message from the server?
+		perr := Error{}
+		if err := json.Unmarshal([]byte(res.Body), &perr); err == nil && perr.Error != "" {
+			//  looks like we have an error from poloniex
+			class := ErrorClassAPI
+			if nonceError.MatchString(perr.Error) {
+				class = ErrorClassNonce
+			}
+			p.metrics.Error(req.Command, class)
+			p.logger.Warn("private request rejected", "command", req.Command, "pair", pairOf(req.Params), "status", res.Status, "error", perr.Error)
+			return res, fmt.Errorf(perr.Error)
+		}
+		return res, nil
+	}
+}
+
+// sendPublic sends a public request to the exchange
+func (p *Poloniex) sendPublic(req *Request) (*Response, error) {
+	return p.send(req, goreq.Request{Uri: PUBLICURI, QueryString: req.Params, Timeout: 130 * time.Second})
+}
+
+// sendPrivate sends a signed private request to the exchange
+func (p *Poloniex) sendPrivate(req *Request) (*Response, error) {
+	r := goreq.Request{
+		Method:      "POST",
+		Uri:         PRIVATEURI,
+		Body:        req.Params.Encode(),
+		ContentType: "application/x-www-form-urlencoded",
+		Accept:      "application/json",
+		Timeout:     130 * time.Second,
+	}
+	for name, values := range req.Header {
+		for _, v := range values {
+			r.AddHeader(name, v)
+		}
+	}
+	return p.send(req, r)
+}
+
+// send makes the HTTP request and reads the body
+func (p *Poloniex) send(req *Request, r goreq.Request) (*Response, error) {
+	kind := "public"
+	if req.Private {
+		kind = "private"
+	}
+	start := time.Now()
+	res, err := r.Do()
+	if err != nil {
+		p.metrics.Error(req.Command, ErrorClassNetwork)
+		p.logger.Warn(kind+" request failed", "command", req.Command, "pair", pairOf(req.Params), "latency", time.Since(start), "error", err)
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	s, err := res.Body.ToString()
+	p.metrics.Request(req.Command, time.Since(start))
+	if err != nil {
+		p.metrics.Error(req.Command, ErrorClassNetwork)
+		p.logger.Warn("reading "+kind+" response failed", "command", req.Command, "pair", pairOf(req.Params), "status", res.StatusCode, "error", err)
+		return &Response{Status: res.StatusCode}, err
+	}
+	p.logger.Debug(kind+" request", "command", req.Command, "pair", pairOf(req.Params), "latency", time.Since(start), "status", res.StatusCode)
+	if p.debug {
+		body := s
+		if req.Private {
+			body = p.redact(s)
+		}
+		p.logger.Debug(kind+" response", "command", req.Command, "body", body)
+	}
+	return &Response{Status: res.StatusCode, Body: s}, nil
+}
@@ -0,0 +1,58 @@
+package poloniex
+
+import (
+	"errors"
+	"fmt"
+)
+
+func ExamplePoloniex_Use() {
+	p := NewREST("key", "secret")
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			if req.Private {
+				// audit private requests, then fail them instead of sending
+				fmt.Println("audit", req.Command, req.Params.Get("currencyPair"))
+				return nil, errors.New("injected fault")
+			}
+			// answer public requests from a cache instead of the exchange
+			return &Response{Status: 200, Body: `{"USDT_BTC":{"id":121,"last":"7000.5"}}`}, nil
+		}
+	})
+	ticker, err := p.Ticker()
+	fmt.Println(ticker["USDT_BTC"].Last, err)
+	_, err = p.OpenOrders("USDT_BTC")
+	fmt.Println(err)
+	// Output:
+	// 7000.5 <nil>
+	// audit returnOpenOrders USDT_BTC
+	// injected fault
+}
+
+func ExampleMiddleware() {
+	p := NewREST("key", "secret")
+	// swallow cancels instead of sending them, leaving the result empty
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			if req.Command == "cancelOrder" {
+				return nil, nil
+			}
+			return next(req)
+		}
+	})
+	ok, err := p.CancelOrder(5)
+	fmt.Println(ok, err)
+	// Output: false <nil>
+}
+
+// answer is middleware standing in for the exchange, answering each command with a canned body
+func answer(bodies map[string]string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			body, ok := bodies[req.Command]
+			if !ok {
+				return nil, fmt.Errorf("unexpected %s", req.Command)
+			}
+			return &Response{Status: 200, Body: body}, nil
+		}
+	}
+}
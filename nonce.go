@@ -0,0 +1,162 @@
+package poloniex
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// nonceRetries is how many times a private request is resent after the exchange rejects its nonce
+const nonceRetries = 3
+
+// nonceError matches the error the exchange returns for a nonce which is too low, capturing the last nonce it accepted
+var nonceError = regexp.MustCompile(`Nonce must be greater than (\d+)`)
+
+type (
+	// NonceSource hands out the strictly increasing nonces private requests are signed with.
+	// Every process using the same API key must draw from the same source, see FileNonce.
+	NonceSource interface {
+		// Next returns a nonce greater than any returned before
+		Next() (int64, error)
+		// Skip makes every later nonce greater than n, it is given the last nonce the exchange accepted after a collision
+		Skip(n int64) error
+	}
+
+	// CounterNonce is a lock free nonce source for a single process, seeded from the clock
+	CounterNonce struct {
+		last int64
+	}
+
+	// FileNonce is a nonce source shared by several processes through a file, which is locked while a nonce is taken
+	// with flock or LockFileEx. Where neither is available it returns an error rather than risk a shared nonce.
+	FileNonce struct {
+		mutex sync.Mutex
+		file  *os.File
+	}
+)
+
+// NewCounterNonce creates a nonce source starting from the current time in nanoseconds
+func NewCounterNonce() *CounterNonce {
+	return &CounterNonce{last: time.Now().UnixNano()}
+}
+
+// Next returns the next nonce
+func (c *CounterNonce) Next() (int64, error) {
+	return atomic.AddInt64(&c.last, 1), nil
+}
+
+// Skip moves the counter past n
+func (c *CounterNonce) Skip(n int64) error {
+	for {
+		last := atomic.LoadInt64(&c.last)
+		if last >= n || atomic.CompareAndSwapInt64(&c.last, last, n) {
+			return nil
+		}
+	}
+}
+
+// NewFileNonce opens, creating if need be, the file holding the last nonce used by any process sharing it
+func NewFileNonce(filename string) (*FileNonce, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening "+filename+" failed")
+	}
+	return &FileNonce{file: f}, nil
+}
+
+// Next locks the file and takes the next nonce, which is also never behind the clock
+func (f *FileNonce) Next() (n int64, err error) {
+	err = f.update(func(last int64) int64 {
+		n = last + 1
+		if now := time.Now().UnixNano(); now > n {
+			n = now
+		}
+		return n
+	})
+	return
+}
+
+// Skip locks the file and moves it past n
+func (f *FileNonce) Skip(n int64) error {
+	return f.update(func(last int64) int64 {
+		if last > n {
+			return last
+		}
+		return n
+	})
+}
+
+// Close closes the file
+func (f *FileNonce) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
+
+// update replaces the last nonce in the file with next(last) while holding the lock
+func (f *FileNonce) update(next func(last int64) int64) error {
+	// the file lock belongs to the process, so goroutines queue on the mutex
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := lockFile(f.file); err != nil {
+		return errors.Wrap(err, "locking nonce file failed")
+	}
+	defer unlockFile(f.file)
+
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "reading nonce file failed")
+	}
+	b := make([]byte, 32)
+	l, err := f.file.Read(b)
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "reading nonce file failed")
+	}
+	last := int64(0)
+	if s := strings.TrimSpace(string(b[:l])); s != "" {
+		if last, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return errors.Wrap(err, "nonce file is corrupt")
+		}
+	}
+	s := strconv.FormatInt(next(last), 10)
+	if err := f.file.Truncate(0); err != nil {
+		return errors.Wrap(err, "writing nonce file failed")
+	}
+	if _, err := f.file.WriteAt([]byte(s), 0); err != nil {
+		return errors.Wrap(err, "writing nonce file failed")
+	}
+	return nil
+}
+
+// SetNonceSource replaces the client's nonce source, it should be called before the client is in use
+func (p *Poloniex) SetNonceSource(ns NonceSource) {
+	p.nonces = ns
+}
+
+func (p *Poloniex) getNonce() (string, error) {
+	n, err := p.nonces.Next()
+	if err != nil {
+		return "", errors.Wrap(err, "taking nonce failed")
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+// skipNonce recovers from a rejected nonce by moving the source past the nonce the exchange expects,
+// reporting whether err was a nonce error
+func (p *Poloniex) skipNonce(err error) bool {
+	m := nonceError.FindStringSubmatch(err.Error())
+	if m == nil {
+		return false
+	}
+	n, perr := strconv.ParseInt(m[1], 10, 64)
+	if perr != nil {
+		return false
+	}
+	return p.nonces.Skip(n) == nil
+}
@@ -0,0 +1,21 @@
+//go:build !windows && !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !windows,!linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package poloniex
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// there is no file locking here, a FileNonce could not be shared safely so it refuses to hand out nonces,
+// use a CounterNonce in a single process instead
+func lockFile(f *os.File) error {
+	return errors.New("file locking is not supported on " + runtime.GOOS)
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
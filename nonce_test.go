@@ -0,0 +1,37 @@
+package poloniex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func ExampleFileNonce() {
+	dir, err := ioutil.TempDir("", "nonce")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "nonce")
+
+	// two sources sharing a file, as two processes using one API key would
+	a, err := NewFileNonce(filename)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer a.Close()
+	b, err := NewFileNonce(filename)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer b.Close()
+
+	first, _ := a.Next()
+	second, _ := b.Next()
+	b.Skip(second + 1000000000000)
+	third, _ := a.Next()
+	fmt.Println(second > first, third > second+1000000000000)
+	// Output: true true
+}
@@ -0,0 +1,18 @@
+package poloniex
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// the whole of the nonce file is locked, it is only ever a few bytes long
+const lockLength = 1 << 20
+
+func lockFile(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, lockLength, 0, &windows.Overlapped{})
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, lockLength, 0, &windows.Overlapped{})
+}
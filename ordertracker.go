@@ -0,0 +1,492 @@
+package poloniex
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OrderState is a stage in the life of an order
+type OrderState int
+
+const (
+	// OrderPending is an order which has been sent but not yet seen on the book
+	OrderPending OrderState = iota
+	// OrderOpen is an order resting on the book with nothing filled
+	OrderOpen
+	// OrderPartiallyFilled is an order resting on the book with some of it filled
+	OrderPartiallyFilled
+	// OrderFilled is an order which has been completely filled
+	OrderFilled
+	// OrderCancelled is an order which was cancelled or killed before completely filling
+	OrderCancelled
+)
+
+// filledEpsilon absorbs rounding when deciding whether an order is completely filled
+const filledEpsilon = 1e-8
+
+type (
+	// TrackedOrder is the state of an order followed by an OrderTracker
+	TrackedOrder struct {
+		OrderNumber int64
+		// Previous holds the order numbers this order had before being moved
+		Previous     []int64
+		Pair         string
+		Type         string
+		Rate         float64
+		Amount       float64
+		Filled       float64
+		Remaining    float64
+		AveragePrice float64
+		Total        float64
+		State        OrderState
+		Trades       []OrderTrade
+		Updated      time.Time
+
+		tradeIDs map[int64]bool
+		moving   bool
+	}
+
+	// OrderTransition is emitted as an "order-transition" event whenever a tracked order changes state,
+	// and also as "order-<state>", for example "order-filled"
+	OrderTransition struct {
+		From  OrderState
+		To    OrderState
+		Order TrackedOrder
+	}
+
+	// OrderTracker follows orders through their life, using the account notifications channel when subscribed
+	// (see SubscribeAccount) and polling the REST API as a fallback.
+	OrderTracker struct {
+		p      *Poloniex
+		poll   time.Duration
+		mutex  sync.Mutex
+		orders map[int64]*TrackedOrder
+		queue  []OrderTransition
+		queued *sync.Cond
+		closed bool
+		remove []func()
+	}
+)
+
+// String returns the name of the state
+func (s OrderState) String() string {
+	switch s {
+	case OrderOpen:
+		return "open"
+	case OrderPartiallyFilled:
+		return "partially-filled"
+	case OrderFilled:
+		return "filled"
+	case OrderCancelled:
+		return "cancelled"
+	}
+	return "pending"
+}
+
+// Done reports whether the state is final
+func (s OrderState) Done() bool {
+	return s == OrderFilled || s == OrderCancelled
+}
+
+// NewOrderTracker creates an order tracker for the client, poll is the REST polling interval (defaults to 10 seconds)
+func NewOrderTracker(p *Poloniex, poll time.Duration) *OrderTracker {
+	if poll <= 0 {
+		poll = 10 * time.Second
+	}
+	t := &OrderTracker{p: p, poll: poll, orders: map[int64]*TrackedOrder{}}
+	t.queued = sync.NewCond(&t.mutex)
+	t.remove = []func(){
+		p.listen("account-new", t.onNew),
+		p.listen("account-update", t.onUpdate),
+		p.listen("account-trade", t.onTrade),
+		p.listen("account-killed", t.onKilled),
+	}
+	go t.dispatch()
+	return t
+}
+
+// OrderTracker returns the client's shared order tracker, creating it on first use.
+// Executions and working orders track their orders with it, it is never closed.
+func (p *Poloniex) OrderTracker() *OrderTracker {
+	p.booksMutex.Lock()
+	defer p.booksMutex.Unlock()
+	if p.tracker == nil {
+		p.tracker = NewOrderTracker(p, 0)
+	}
+	return p.tracker
+}
+
+// Close stops the tracker following account notifications and emitting transitions
+func (t *OrderTracker) Close() {
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		return
+	}
+	t.closed = true
+	t.mutex.Unlock()
+	for _, remove := range t.remove {
+		remove()
+	}
+	t.queued.Signal()
+}
+
+// dispatch emits transitions in the order they happened, outside the lock so listeners may call back into the tracker
+func (t *OrderTracker) dispatch() {
+	for {
+		t.mutex.Lock()
+		for len(t.queue) == 0 && !t.closed {
+			t.queued.Wait()
+		}
+		if t.closed {
+			t.mutex.Unlock()
+			return
+		}
+		queue := t.queue
+		t.queue = nil
+		t.mutex.Unlock()
+		t.p.metrics.QueueDepth("order-tracker", 0)
+		for _, tr := range queue {
+			t.p.Emit("order-transition", tr).Emit("order-"+tr.To.String(), tr)
+		}
+	}
+}
+
+// Track starts following an order placed elsewhere
+func (t *OrderTracker) Track(orderNumber int64, pair, typ string, rate, amount float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, ok := t.orders[orderNumber]; ok {
+		return
+	}
+	t.orders[orderNumber] = &TrackedOrder{
+		OrderNumber: orderNumber, Pair: pair, Type: typ, Rate: rate, Amount: amount, Remaining: amount,
+		Updated: time.Now(), tradeIDs: map[int64]bool{},
+	}
+}
+
+// Buy places a limit buy order and tracks it
+func (t *OrderTracker) Buy(pair string, rate, amount float64) (buy Buy, err error) {
+	buy, err = t.p.Buy(pair, rate, amount)
+	if err == nil {
+		t.placed(buy, pair, "buy", rate, amount)
+	}
+	return
+}
+
+// Sell places a limit sell order and tracks it
+func (t *OrderTracker) Sell(pair string, rate, amount float64) (sell Sell, err error) {
+	sell, err = t.p.Sell(pair, rate, amount)
+	if err == nil {
+		t.placed(sell.Buy, pair, "sell", rate, amount)
+	}
+	return
+}
+
+// BuyPostOnly places a post only limit buy order and tracks it
+func (t *OrderTracker) BuyPostOnly(pair string, rate, amount float64) (buy Buy, err error) {
+	buy, err = t.p.BuyPostOnly(pair, rate, amount)
+	if err == nil {
+		t.placed(buy, pair, "buy", rate, amount)
+	}
+	return
+}
+
+// SellPostOnly places a post only limit sell order and tracks it
+func (t *OrderTracker) SellPostOnly(pair string, rate, amount float64) (sell Sell, err error) {
+	sell, err = t.p.SellPostOnly(pair, rate, amount)
+	if err == nil {
+		t.placed(sell.Buy, pair, "sell", rate, amount)
+	}
+	return
+}
+
+func (t *OrderTracker) placed(b Buy, pair, typ string, rate, amount float64) {
+	t.Track(b.OrderNumber, pair, typ, rate, amount)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	o := t.orders[b.OrderNumber]
+	for _, v := range b.ResultingTrades {
+		t.fill(o, v.TradeID, OrderTrade{CurrencyPair: pair, Type: v.Type, Rate: v.Rate, Amount: v.Amount, Total: v.Total, Fee: v.Fee, Date: v.Date})
+	}
+	// the order is live once the API has accepted it
+	if o.State == OrderPending {
+		t.transition(o, OrderOpen)
+	}
+}
+
+// Move moves a tracked order to a new rate, following it to its new order number
+func (t *OrderTracker) Move(orderNumber int64, rate float64) (moveOrder MoveOrder, err error) {
+	return t.move(orderNumber, rate, t.p.Move)
+}
+
+// MovePostOnly moves a tracked order to a new rate as a post only order, following it to its new order number
+func (t *OrderTracker) MovePostOnly(orderNumber int64, rate float64) (moveOrder MoveOrder, err error) {
+	return t.move(orderNumber, rate, t.p.MovePostOnly)
+}
+
+func (t *OrderTracker) move(orderNumber int64, rate float64, move func(int64, float64) (MoveOrder, error)) (MoveOrder, error) {
+	t.mutex.Lock()
+	if o, ok := t.orders[orderNumber]; ok {
+		// the cancel of the old order number may be notified before the move returns
+		o.moving = true
+	}
+	t.mutex.Unlock()
+
+	m, err := move(orderNumber, rate)
+	if err == nil && m.Success != 1 {
+		err = errors.New(m.Error)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	o, ok := t.orders[orderNumber]
+	if !ok {
+		return m, err
+	}
+	o.moving = false
+	if err != nil {
+		return m, err
+	}
+	delete(t.orders, orderNumber)
+	o.Previous = append(o.Previous, orderNumber)
+	o.OrderNumber = m.OrderNumber
+	o.Rate = rate
+	o.Updated = time.Now()
+	t.orders[m.OrderNumber] = o
+	for _, v := range m.ResultingTrades {
+		t.fill(o, v.TradeID, OrderTrade{CurrencyPair: o.Pair, Type: v.Type, Rate: v.Rate, Amount: v.Amount, Total: v.Total, Fee: v.Fee, Date: v.Date})
+	}
+	return m, nil
+}
+
+// Cancel cancels a tracked order
+func (t *OrderTracker) Cancel(orderNumber int64) (success bool, err error) {
+	success, err = t.p.CancelOrder(orderNumber)
+	if err == nil && success {
+		t.mutex.Lock()
+		if o, ok := t.orders[orderNumber]; ok && !o.State.Done() {
+			t.transition(o, OrderCancelled)
+		}
+		t.mutex.Unlock()
+	}
+	return
+}
+
+// Order returns the current state of a tracked order, by its current or any previous order number
+func (t *OrderTracker) Order(orderNumber int64) (TrackedOrder, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if o := t.find(orderNumber); o != nil {
+		return o.snapshot(), true
+	}
+	return TrackedOrder{}, false
+}
+
+// Orders returns the current state of every tracked order
+func (t *OrderTracker) Orders() []TrackedOrder {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	orders := make([]TrackedOrder, 0, len(t.orders))
+	for _, o := range t.orders {
+		orders = append(orders, o.snapshot())
+	}
+	return orders
+}
+
+// Forget stops tracking an order
+func (t *OrderTracker) Forget(orderNumber int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if o := t.find(orderNumber); o != nil {
+		delete(t.orders, o.OrderNumber)
+	}
+}
+
+// Run polls the REST API for orders which are not yet done until ctx is done
+func (t *OrderTracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := t.Poll(); err != nil {
+				t.p.Emit("order-error", err)
+			}
+		}
+	}
+}
+
+// Poll brings every tracked order which is not yet done up to date from the REST API
+func (t *OrderTracker) Poll() error {
+	t.mutex.Lock()
+	pairs := map[string]bool{}
+	for _, o := range t.orders {
+		if !o.State.Done() && !o.moving {
+			pairs[o.Pair] = true
+		}
+	}
+	t.mutex.Unlock()
+	if len(pairs) == 0 {
+		return nil
+	}
+	open, err := t.p.OpenOrdersAll()
+	if err != nil {
+		return errors.Wrap(err, "fetching open orders failed")
+	}
+
+	t.mutex.Lock()
+	stale := map[int64]float64{}
+	for _, o := range t.orders {
+		if o.State.Done() || o.moving {
+			continue
+		}
+		remaining, found := -1.0, false
+		for _, v := range open[o.Pair] {
+			if v.OrderNumber == o.OrderNumber {
+				remaining, found = v.Amount, true
+				break
+			}
+		}
+		if !found || remaining < o.Remaining-filledEpsilon {
+			stale[o.OrderNumber] = remaining
+		} else if o.State == OrderPending {
+			t.transition(o, OrderOpen)
+		}
+	}
+	t.mutex.Unlock()
+
+	// orders which have traded or left the book need their trades
+	for n, remaining := range stale {
+		trades, err := t.p.OrderTrades(n)
+		if err != nil {
+			return errors.Wrap(err, "fetching order trades failed")
+		}
+		t.mutex.Lock()
+		if o, ok := t.orders[n]; ok && !o.moving {
+			for _, v := range trades {
+				t.fill(o, strconv.FormatInt(v.TradeID, 10), v)
+			}
+			if remaining < 0 && !o.State.Done() {
+				// gone from the book without filling completely
+				t.transition(o, OrderCancelled)
+			}
+		}
+		t.mutex.Unlock()
+	}
+	return nil
+}
+
+func (t *OrderTracker) onNew(m WSAccountOrder) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if o, ok := t.orders[m.OrderNumber]; ok && !t.closed && o.State == OrderPending {
+		t.transition(o, OrderOpen)
+	}
+}
+
+func (t *OrderTracker) onUpdate(m WSAccountUpdate) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	o, ok := t.orders[m.OrderNumber]
+	if !ok || t.closed || o.State.Done() || o.moving {
+		return
+	}
+	if m.Reason == "cancel" || m.Reason == "self-trade" {
+		t.transition(o, OrderCancelled)
+	}
+}
+
+func (t *OrderTracker) onTrade(m WSAccountTrade) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	o, ok := t.orders[m.OrderNumber]
+	if !ok || t.closed {
+		return
+	}
+	fee := 0.0
+	if m.Total > 0 {
+		fee = m.TotalFee / m.Total
+	}
+	t.fill(o, strconv.FormatInt(m.TradeID, 10), OrderTrade{
+		TradeID: m.TradeID, CurrencyPair: o.Pair, Type: o.Type, Rate: m.Rate, Amount: m.Amount,
+		Total: m.Rate * m.Amount, Fee: fee, Date: m.TS.UTC().Format(timeLayout),
+	})
+}
+
+func (t *OrderTracker) onKilled(m WSAccountKilled) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if o, ok := t.orders[m.OrderNumber]; ok && !t.closed && !o.State.Done() {
+		t.transition(o, OrderCancelled)
+	}
+}
+
+// find looks an order up by its current or a previous order number, the mutex must be held
+func (t *OrderTracker) find(orderNumber int64) *TrackedOrder {
+	if o, ok := t.orders[orderNumber]; ok {
+		return o
+	}
+	for _, o := range t.orders {
+		for _, n := range o.Previous {
+			if n == orderNumber {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// fill adds a trade to the order once, and moves it to the state the fills imply, the mutex must be held
+func (t *OrderTracker) fill(o *TrackedOrder, tradeID string, trade OrderTrade) {
+	id, err := strconv.ParseInt(tradeID, 10, 64)
+	if err == nil {
+		if o.tradeIDs[id] {
+			return
+		}
+		o.tradeIDs[id] = true
+		trade.TradeID = id
+	}
+	o.Trades = append(o.Trades, trade)
+	o.Filled += trade.Amount
+	o.Total += trade.Total
+	o.Remaining = o.Amount - o.Filled
+	if o.Filled > 0 {
+		o.AveragePrice = o.Total / o.Filled
+	}
+	o.Updated = time.Now()
+	switch {
+	case o.Remaining <= filledEpsilon:
+		t.transition(o, OrderFilled)
+	case o.State != OrderCancelled:
+		t.transition(o, OrderPartiallyFilled)
+	}
+}
+
+// transition moves the order to a new state and emits the change, the mutex must be held
+func (t *OrderTracker) transition(o *TrackedOrder, to OrderState) {
+	if o.State == to {
+		return
+	}
+	tr := OrderTransition{From: o.State, To: to}
+	o.State = to
+	o.Updated = time.Now()
+	tr.Order = o.snapshot()
+	t.queue = append(t.queue, tr)
+	t.p.metrics.QueueDepth("order-tracker", len(t.queue))
+	t.queued.Signal()
+}
+
+func (o *TrackedOrder) snapshot() TrackedOrder {
+	s := *o
+	s.Previous = append([]int64{}, o.Previous...)
+	s.Trades = append([]OrderTrade{}, o.Trades...)
+	s.tradeIDs = nil
+	return s
+}
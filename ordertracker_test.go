@@ -0,0 +1,33 @@
+package poloniex
+
+import (
+	"fmt"
+)
+
+func ExampleOrderTracker() {
+	p := NewREST("key", "secret")
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			return &Response{Status: 200, Body: `{"orderNumber":"31226040","resultingTrades":[]}`}, nil
+		}
+	})
+	t := p.OrderTracker()
+	buy, err := t.Buy("USDT_BTC", 7000, 0.1)
+	fmt.Println(buy.OrderNumber, err)
+
+	// other trackers on the same client come and go without disturbing the shared one
+	trackers := []*OrderTracker{}
+	for i := 0; i < 20; i++ {
+		trackers = append(trackers, NewOrderTracker(p, 0))
+	}
+	for _, other := range trackers {
+		other.Close()
+	}
+
+	p.Emit("account-update", WSAccountUpdate{OrderNumber: buy.OrderNumber, Reason: "cancel"})
+	o, _ := t.Order(buy.OrderNumber)
+	fmt.Println(o.State, p.OrderTracker() == t)
+	// Output:
+	// 31226040 <nil>
+	// cancelled true
+}
@@ -0,0 +1,459 @@
+package poloniex
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LotMethod selects how sells are matched against earlier buys
+type LotMethod int
+
+const (
+	// FIFO matches the oldest lots first
+	FIFO LotMethod = iota
+	// LIFO matches the newest lots first
+	LIFO
+	// AverageCost values all traded lots of a currency at their average cost, each keeping the date it was acquired
+	AverageCost
+)
+
+type (
+	// PnL computes realised and unrealised profit and loss from your trade history.
+	// Every trade disposes of one currency and acquires the other: a buy on BTC_ETH disposes of BTC, matching
+	// its lots by Method, and acquires an ETH lot, a sell does the reverse. Both sides are valued in Currency at
+	// the time of the trade, using the rate of the trade itself and the latest rates before it from the other
+	// trades and any added with AddRates, so a lot's cost is fixed when it is acquired. Currency itself is not
+	// tracked in lots. Deposits create zero cost lots which are only used once the traded lots are exhausted,
+	// withdrawals consume deposited lots first. Only exchange trades are used unless IncludeMargin is set.
+	PnL struct {
+		Method LotMethod
+		// Currency is what costs, proceeds and gains are measured in, USDT if empty
+		Currency      string
+		IncludeMargin bool
+		events        []pnlEvent
+	}
+
+	pnlEvent struct {
+		ts     time.Time
+		id     int64
+		kind   string
+		pair   string
+		trade  PrivateTradeHistoryEntry
+		amount float64
+	}
+
+	// Lot is an amount of a currency acquired at a single time and cost
+	Lot struct {
+		// Pair is the market the lot was traded on, empty for deposits
+		Pair     string
+		Currency string
+		Amount   float64
+		// Cost is the value of what was given for the lot including fees, in the PnL's Currency when it was acquired
+		Cost     float64
+		Acquired time.Time
+	}
+
+	// Disposal is the sale of all or part of a lot, or its use to buy another currency
+	Disposal struct {
+		// Pair is the market the lot was disposed of on
+		Pair     string
+		Currency string
+		Amount   float64
+		Acquired time.Time
+		Disposed time.Time
+		// Proceeds, Cost and Gain are in CostCurrency, the PnL's Currency
+		Proceeds     float64
+		Cost         float64
+		Gain         float64
+		CostCurrency string
+		// Deposited is set when the lot came from a deposit, and so has no known cost
+		Deposited bool
+	}
+
+	// PairPnL is the profit and loss of a single market, in the PnL's Currency.
+	// Realised is from the disposals made on the market, Position and CostBasis are what is still held of the lots bought on it.
+	PairPnL struct {
+		Pair       string
+		Currency   string
+		Bought     float64
+		Sold       float64
+		Position   float64
+		CostBasis  float64
+		Realised   float64
+		Unrealised float64
+		// Fees are keyed by the currency they were paid in
+		Fees map[string]float64
+	}
+
+	// CurrencyPnL totals the profit and loss, in the PnL's Currency, of disposing of and holding a single currency,
+	// deposited lots counting at zero cost. Fees are the amount of the currency paid in fees.
+	CurrencyPnL struct {
+		Currency   string
+		Realised   float64
+		Unrealised float64
+		Fees       float64
+		// Position is the amount of the currency still held in lots
+		Position float64
+	}
+
+	// PnLReport is the result of a PnL calculation
+	PnLReport struct {
+		Method LotMethod
+		// Currency is what all profit and loss is measured in
+		Currency   string
+		Pairs      []PairPnL
+		Currencies []CurrencyPnL
+		Disposals  []Disposal
+		// Unpriced lists currencies traded when there was no route to Currency, these are valued at zero
+		Unpriced []string
+	}
+
+	pnlState struct {
+		method   LotMethod
+		currency string
+		// prices holds the latest rate of every market as events are replayed, for valuing trades in currency
+		prices   *Prices
+		lots     map[string][]*Lot
+		pairs    map[string]*PairPnL
+		disp     []Disposal
+		unpriced map[string]bool
+	}
+)
+
+// NewPnL creates a PnL calculator using the lot matching method given
+func NewPnL(method LotMethod) *PnL {
+	return &PnL{Method: method}
+}
+
+// AddTrades adds trade history for all markets, as returned by PrivateTradeHistoryAll
+func (pl *PnL) AddTrades(history PrivateTradeHistoryAll) error {
+	for pair, trades := range history {
+		if err := pl.AddPairTrades(pair, trades); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPairTrades adds trade history for a single market, as returned by PrivateTradeHistory
+func (pl *PnL) AddPairTrades(pair string, history PrivateTradeHistory) error {
+	for _, v := range history {
+		ts, err := parseTime(v.Date)
+		if err != nil {
+			return errors.Wrap(err, "parsing trade date failed")
+		}
+		pl.events = append(pl.events, pnlEvent{ts: ts, id: v.GlobalTradeID, kind: v.Type, pair: pair, trade: v})
+	}
+	return nil
+}
+
+// AddRates adds market rates, such as from ChartData, for valuing trades between currencies other than the PnL's
+// Currency at the time they were made rather than at the rate of your last trade linking them
+func (pl *PnL) AddRates(pair string, chart ChartData) {
+	for _, v := range chart {
+		pl.events = append(pl.events, pnlEvent{ts: time.Unix(v.Date, 0), kind: "rate", pair: pair, amount: v.WeightedAverage})
+	}
+}
+
+// AddDepositsWithdrawals adds completed deposits, withdrawals and adjustments, as returned by DepositsWithdrawals,
+// positive adjustments are treated as deposits and negative ones as withdrawals
+func (pl *PnL) AddDepositsWithdrawals(dw DepositsWithdrawals) {
+	for _, v := range dw.Deposits {
+		if isComplete(v.Status) {
+			pl.events = append(pl.events, pnlEvent{ts: time.Unix(v.Timestamp, 0), kind: "deposit", pair: v.Currency, amount: v.Amount})
+		}
+	}
+	for _, v := range dw.Withdrawals {
+		if isComplete(v.Status) {
+			pl.events = append(pl.events, pnlEvent{ts: time.Unix(v.Timestamp, 0), kind: "withdrawal", pair: v.Currency, amount: v.Amount})
+		}
+	}
+	for _, v := range dw.Adjustments {
+		if !isComplete(v.Status) || v.Amount == 0 {
+			continue
+		}
+		e := pnlEvent{ts: time.Unix(v.Timestamp, 0), kind: "deposit", pair: v.Currency, amount: v.Amount}
+		if v.Amount < 0 {
+			e.kind, e.amount = "withdrawal", -v.Amount
+		}
+		pl.events = append(pl.events, e)
+	}
+}
+
+// isComplete reports whether a status is a completed one, withdrawals carry the transaction id e.g. "COMPLETE: 0x..."
+func isComplete(status string) bool {
+	return len(status) >= 8 && status[:8] == "COMPLETE"
+}
+
+// Report calculates profit and loss, prices is used for the unrealised figures and may be nil
+func (pl *PnL) Report(prices *Prices) PnLReport {
+	s := pl.run()
+	report := PnLReport{Method: pl.Method, Currency: s.currency, Disposals: s.disp}
+	currencies := map[string]*CurrencyPnL{}
+	currency := func(c string) *CurrencyPnL {
+		if currencies[c] == nil {
+			currencies[c] = &CurrencyPnL{Currency: c}
+		}
+		return currencies[c]
+	}
+	unrealised := func(amount, cost float64, c string) float64 {
+		if prices == nil || amount <= 0 {
+			return 0
+		}
+		if value, ok := prices.Convert(amount, c, s.currency); ok {
+			return value - cost
+		}
+		return 0
+	}
+	for pair, v := range s.pairs {
+		_, quote := splitPair(pair)
+		for _, l := range s.lots[quote] {
+			if l.Pair == pair {
+				v.Position += l.Amount
+				v.CostBasis += l.Cost
+			}
+		}
+		v.Unrealised = unrealised(v.Position, v.CostBasis, quote)
+		for fc, fee := range v.Fees {
+			currency(fc).Fees += fee
+		}
+		report.Pairs = append(report.Pairs, *v)
+	}
+	for _, d := range s.disp {
+		currency(d.Currency).Realised += d.Gain
+	}
+	for c, lots := range s.lots {
+		cost := 0.0
+		for _, l := range lots {
+			currency(c).Position += l.Amount
+			cost += l.Cost
+		}
+		currency(c).Unrealised = unrealised(currency(c).Position, cost, c)
+	}
+	for _, v := range currencies {
+		report.Currencies = append(report.Currencies, *v)
+	}
+	for c := range s.unpriced {
+		report.Unpriced = append(report.Unpriced, c)
+	}
+	sort.Strings(report.Unpriced)
+	sort.Slice(report.Pairs, func(i, j int) bool { return report.Pairs[i].Pair < report.Pairs[j].Pair })
+	sort.Slice(report.Currencies, func(i, j int) bool { return report.Currencies[i].Currency < report.Currencies[j].Currency })
+	return report
+}
+
+// Lots returns the lots still held after all trades, keyed by currency
+func (pl *PnL) Lots() map[string][]Lot {
+	s := pl.run()
+	lots := map[string][]Lot{}
+	for k, v := range s.lots {
+		for _, l := range v {
+			lots[k] = append(lots[k], *l)
+		}
+	}
+	return lots
+}
+
+// run replays every event in time order, events may be added in any order
+func (pl *PnL) run() *pnlState {
+	events := append([]pnlEvent{}, pl.events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].ts.Equal(events[j].ts) {
+			return events[i].id < events[j].id
+		}
+		return events[i].ts.Before(events[j].ts)
+	})
+	s := &pnlState{
+		method: pl.Method, currency: pl.Currency, prices: NewPrices(nil),
+		lots: map[string][]*Lot{}, pairs: map[string]*PairPnL{}, unpriced: map[string]bool{},
+	}
+	if s.currency == "" {
+		s.currency = "USDT"
+	}
+	for _, e := range events {
+		switch e.kind {
+		case "buy", "sell":
+			if e.trade.Category != "exchange" && e.trade.Category != "" && !pl.IncludeMargin {
+				continue
+			}
+			s.trade(e)
+		case "rate":
+			s.prices.Update(WSTicker{Pair: e.pair, Last: e.amount})
+		case "deposit":
+			if e.pair != s.currency {
+				s.lots[e.pair] = append(s.lots[e.pair], &Lot{Currency: e.pair, Amount: e.amount, Acquired: e.ts})
+			}
+		case "withdrawal":
+			s.withdraw(e.pair, e.amount)
+		}
+	}
+	return s
+}
+
+func (s *pnlState) pair(pair string) *PairPnL {
+	if s.pairs[pair] == nil {
+		s.pairs[pair] = &PairPnL{Pair: pair, Currency: s.currency, Fees: map[string]float64{}}
+	}
+	return s.pairs[pair]
+}
+
+// trade disposes of what was given and acquires what was received, both valued at what was given
+func (s *pnlState) trade(e pnlEvent) {
+	base, quote := splitPair(e.pair)
+	p := s.pair(e.pair)
+	t := e.trade
+	s.prices.Update(WSTicker{Pair: e.pair, Last: t.Rate})
+	if t.Type == "buy" {
+		// the fee is taken from the currency received
+		fee := t.Amount * t.Fee
+		p.Fees[quote] += fee
+		p.Bought += t.Amount
+		value := s.value(t.Total, base)
+		s.dispose(e, base, t.Total, value)
+		s.acquire(e, quote, t.Amount-fee, value)
+		return
+	}
+	fee := t.Total * t.Fee
+	p.Fees[base] += fee
+	p.Sold += t.Amount
+	value := s.value(t.Total-fee, base)
+	s.dispose(e, quote, t.Amount, value)
+	s.acquire(e, base, t.Total-fee, value)
+}
+
+// value converts amount of currency to the PnL's currency at the rates reached so far in the replay
+func (s *pnlState) value(amount float64, currency string) float64 {
+	if currency == s.currency {
+		return amount
+	}
+	value, ok := s.prices.Convert(amount, currency, s.currency)
+	if !ok {
+		s.unpriced[currency] = true
+	}
+	return value
+}
+
+// acquire adds a lot costing cost, the PnL's currency is not held in lots
+func (s *pnlState) acquire(e pnlEvent, currency string, amount, cost float64) {
+	if currency == s.currency || amount <= 0 {
+		return
+	}
+	s.lots[currency] = append(s.lots[currency], &Lot{Pair: e.pair, Currency: currency, Amount: amount, Cost: cost, Acquired: e.ts})
+	if s.method == AverageCost {
+		s.average(currency)
+	}
+}
+
+// dispose matches amount of currency against its lots, traded lots first then deposited ones, sharing proceeds between them
+func (s *pnlState) dispose(e pnlEvent, currency string, amount, proceeds float64) {
+	if currency == s.currency || amount <= 0 {
+		return
+	}
+	p := s.pair(e.pair)
+	remaining := amount
+	for _, traded := range []bool{true, false} {
+		for remaining > 0 {
+			l := s.next(currency, func(l *Lot) bool { return (l.Pair != "") == traded })
+			if l == nil {
+				break
+			}
+			taken, cost := s.take(l, remaining)
+			share := proceeds * taken / amount
+			d := Disposal{
+				Pair: e.pair, Currency: currency, Amount: taken, Acquired: l.Acquired, Disposed: e.ts,
+				Proceeds: share, Cost: cost, Gain: share - cost, CostCurrency: s.currency, Deposited: !traded,
+			}
+			s.disp = append(s.disp, d)
+			p.Realised += d.Gain
+			remaining -= taken
+			s.prune(currency)
+		}
+	}
+	if remaining > 1e-12 {
+		// disposed of more than we know about, treat as zero cost
+		share := proceeds * remaining / amount
+		s.disp = append(s.disp, Disposal{
+			Pair: e.pair, Currency: currency, Amount: remaining, Disposed: e.ts,
+			Proceeds: share, Gain: share, CostCurrency: s.currency, Deposited: true,
+		})
+		p.Realised += share
+	}
+}
+
+// average spreads the cost of the traded lots of a currency evenly over them
+func (s *pnlState) average(currency string) {
+	amount, cost := 0.0, 0.0
+	for _, l := range s.lots[currency] {
+		if l.Pair != "" {
+			amount += l.Amount
+			cost += l.Cost
+		}
+	}
+	if amount <= 0 {
+		return
+	}
+	for _, l := range s.lots[currency] {
+		if l.Pair != "" {
+			l.Cost = cost * l.Amount / amount
+		}
+	}
+}
+
+// withdraw removes lots without realising anything, deposited lots go first
+func (s *pnlState) withdraw(currency string, amount float64) {
+	for _, deposited := range []bool{true, false} {
+		for amount > 0 {
+			l := s.next(currency, func(l *Lot) bool { return (l.Pair == "") == deposited })
+			if l == nil {
+				break
+			}
+			taken, _ := s.take(l, amount)
+			amount -= taken
+			s.prune(currency)
+		}
+	}
+}
+
+// next returns the lot to match next according to the lot method
+func (s *pnlState) next(currency string, match func(*Lot) bool) *Lot {
+	lots := s.lots[currency]
+	if s.method == LIFO {
+		for i := len(lots) - 1; i >= 0; i-- {
+			if match(lots[i]) {
+				return lots[i]
+			}
+		}
+		return nil
+	}
+	for _, l := range lots {
+		if match(l) {
+			return l
+		}
+	}
+	return nil
+}
+
+// take removes up to amount from the lot, returning the amount taken and its cost
+func (s *pnlState) take(l *Lot, amount float64) (float64, float64) {
+	if amount >= l.Amount {
+		amount, cost := l.Amount, l.Cost
+		l.Amount, l.Cost = 0, 0
+		return amount, cost
+	}
+	cost := l.Cost * amount / l.Amount
+	l.Amount -= amount
+	l.Cost -= cost
+	return amount, cost
+}
+
+func (s *pnlState) prune(currency string) {
+	lots := s.lots[currency][:0]
+	for _, l := range s.lots[currency] {
+		if l.Amount > 1e-12 {
+			lots = append(lots, l)
+		}
+	}
+	s.lots[currency] = lots
+}
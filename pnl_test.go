@@ -0,0 +1,64 @@
+package poloniex
+
+import (
+	"fmt"
+	"time"
+)
+
+func ExamplePnL() {
+	pl := NewPnL(FIFO)
+	pl.AddPairTrades("USDT_BTC", PrivateTradeHistory{
+		{Date: "2019-01-01 00:00:00", Type: "buy", Rate: 4000, Amount: 1, Total: 4000, Category: "exchange", GlobalTradeID: 1},
+		{Date: "2019-02-01 00:00:00", Type: "buy", Rate: 3500, Amount: 1, Total: 3500, Category: "exchange", GlobalTradeID: 2},
+		{Date: "2019-06-01 00:00:00", Type: "sell", Rate: 8000, Amount: 1, Total: 8000, Category: "exchange", GlobalTradeID: 3},
+	})
+	prices := NewPrices(Ticker{"USDT_BTC": {Last: 9000}})
+	report := pl.Report(prices)
+	for _, v := range report.Pairs {
+		fmt.Println(v.Pair, v.Position, v.Realised, v.Unrealised)
+	}
+	// Output: USDT_BTC 1 4000 5500
+}
+
+func ExamplePnL_crossMarket() {
+	pl := NewPnL(FIFO)
+	pl.AddTrades(PrivateTradeHistoryAll{
+		"USDT_BTC": {
+			{Date: "2019-01-01 00:00:00", Type: "buy", Rate: 4000, Amount: 1, Total: 4000, GlobalTradeID: 1},
+			{Date: "2019-04-01 00:00:00", Type: "sell", Rate: 5000, Amount: 1.1, Total: 5500, GlobalTradeID: 4},
+		},
+		// ETH bought with BTC and sold for BTC, each trade disposes of one and acquires the other
+		"BTC_ETH": {
+			{Date: "2019-02-01 00:00:00", Type: "buy", Rate: 0.02, Amount: 50, Total: 1, GlobalTradeID: 2},
+			{Date: "2019-03-01 00:00:00", Type: "sell", Rate: 0.022, Amount: 50, Total: 1.1, GlobalTradeID: 3},
+		},
+	})
+	// BTC is valued in USDT at the time of each trade, from the rates added here
+	pl.AddRates("USDT_BTC", ChartData{
+		{Date: time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC).Unix(), WeightedAverage: 3500},
+		{Date: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC).Unix(), WeightedAverage: 3800},
+	})
+	for _, d := range pl.Report(nil).Disposals {
+		fmt.Println(d.Pair, d.Amount, d.Currency, d.Acquired.Format("2006-01-02"), d.Proceeds, d.Cost, d.Gain, d.Deposited)
+	}
+	// Output:
+	// BTC_ETH 1 BTC 2019-01-01 3500 4000 -500 false
+	// BTC_ETH 50 ETH 2019-02-01 4180 3500 680 false
+	// USDT_BTC 1.1 BTC 2019-03-01 5500 4180 1320 false
+}
+
+func ExamplePnL_averageCost() {
+	pl := NewPnL(AverageCost)
+	pl.AddPairTrades("USDT_BTC", PrivateTradeHistory{
+		{Date: "2019-01-01 00:00:00", Type: "buy", Rate: 4000, Amount: 1, Total: 4000, GlobalTradeID: 1},
+		{Date: "2019-02-01 00:00:00", Type: "buy", Rate: 3000, Amount: 1, Total: 3000, GlobalTradeID: 2},
+		{Date: "2019-06-01 00:00:00", Type: "sell", Rate: 8000, Amount: 1.5, Total: 12000, GlobalTradeID: 3},
+	})
+	// both lots are costed at the average, but keep their own dates
+	for _, d := range pl.Report(nil).Disposals {
+		fmt.Println(d.Amount, d.Acquired.Format("2006-01-02"), d.Cost, d.Gain)
+	}
+	// Output:
+	// 1 2019-01-01 3500 4500
+	// 0.5 2019-02-01 1750 2250
+}
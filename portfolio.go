@@ -0,0 +1,212 @@
+package poloniex
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Prices converts amounts between currencies using market prices,
+	// routing through intermediate markets when there is no direct market between two currencies.
+	Prices struct {
+		mutex sync.RWMutex
+		last  map[string]float64
+	}
+
+	// Holding is the amount of a single currency held in one account, and its value
+	Holding struct {
+		Account  string
+		Currency string
+		Amount   float64
+		Value    float64
+	}
+
+	// Portfolio is the value of all accounts in a single currency
+	Portfolio struct {
+		Currency string
+		Holdings []Holding
+		Exchange float64
+		Margin   float64
+		// MarginPL is the unrealised profit/loss of open margin positions less lending fees
+		MarginPL float64
+		// Lending includes the available lending balance, open loan offers and active loans
+		Lending float64
+		Total   float64
+		// Unpriced lists currencies for which no route to Currency could be found, these are valued at zero
+		Unpriced []string
+	}
+)
+
+// NewPrices creates a price lookup from the last prices in a ticker
+func NewPrices(ticker Ticker) *Prices {
+	pr := &Prices{last: map[string]float64{}}
+	for k, v := range ticker {
+		if v.IsFrozen == 0 && v.Last > 0 {
+			pr.last[k] = v.Last
+		}
+	}
+	return pr
+}
+
+// Prices fetches the ticker and builds a price lookup from it
+func (p *Poloniex) Prices() (*Prices, error) {
+	t, err := p.Ticker()
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching ticker failed")
+	}
+	return NewPrices(t), nil
+}
+
+// Update sets the price of a single market, it can be registered directly as a "ticker" listener to keep prices live
+func (pr *Prices) Update(t WSTicker) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	if t.IsFrozen || t.Last <= 0 {
+		delete(pr.last, t.Pair)
+		return
+	}
+	pr.last[t.Pair] = t.Last
+}
+
+// Rate returns how many units of to one unit of from is worth, following the route with the fewest markets
+func (pr *Prices) Rate(from, to string) (rate float64, ok bool) {
+	if from == to {
+		return 1, true
+	}
+	pr.mutex.RLock()
+	defer pr.mutex.RUnlock()
+	edges := map[string]map[string]float64{}
+	add := func(a, b string, r float64) {
+		if edges[a] == nil {
+			edges[a] = map[string]float64{}
+		}
+		edges[a][b] = r
+	}
+	for k, v := range pr.last {
+		base, quote := splitPair(k)
+		add(quote, base, v)
+		add(base, quote, 1/v)
+	}
+	// breadth first, so the first time we reach a currency is via the fewest hops
+	rates := map[string]float64{from: 1}
+	queue := []string{from}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		// sorted so the route chosen between equal length routes is stable
+		next := make([]string, 0, len(edges[c]))
+		for n := range edges[c] {
+			next = append(next, n)
+		}
+		sort.Strings(next)
+		for _, n := range next {
+			if _, seen := rates[n]; seen {
+				continue
+			}
+			rates[n] = rates[c] * edges[c][n]
+			if n == to {
+				return rates[n], true
+			}
+			queue = append(queue, n)
+		}
+	}
+	return 0, false
+}
+
+// Convert converts amount of from into to
+func (pr *Prices) Convert(amount float64, from, to string) (float64, bool) {
+	rate, ok := pr.Rate(from, to)
+	return amount * rate, ok
+}
+
+// Portfolio values your exchange, margin and lending accounts in the currency given, e.g. USDT
+func (p *Poloniex) Portfolio(currency string) (portfolio Portfolio, err error) {
+	prices, err := p.Prices()
+	if err != nil {
+		return
+	}
+	balances, err := p.Balances()
+	if err != nil {
+		return portfolio, errors.Wrap(err, "fetching balances failed")
+	}
+	accounts, err := p.AvailableAccountBalances()
+	if err != nil {
+		return portfolio, errors.Wrap(err, "fetching account balances failed")
+	}
+	summary, err := p.MarginAccountSummary()
+	if err != nil {
+		return portfolio, errors.Wrap(err, "fetching margin account summary failed")
+	}
+	offers, err := p.OpenLoanOffers()
+	if err != nil {
+		return portfolio, errors.Wrap(err, "fetching open loan offers failed")
+	}
+	loans, err := p.ActiveLoans()
+	if err != nil {
+		return portfolio, errors.Wrap(err, "fetching active loans failed")
+	}
+	return valuePortfolio(currency, prices, balances, accounts, summary, offers, loans), nil
+}
+
+func valuePortfolio(currency string, prices *Prices, balances Balances, accounts AvailableAccountBalances, summary MarginAccountSummary, offers OpenLoanOffers, loans ActiveLoans) (portfolio Portfolio) {
+	portfolio.Currency = currency
+	unpriced := map[string]bool{}
+	value := func(amount float64, from string) float64 {
+		v, ok := prices.Convert(amount, from, currency)
+		if !ok {
+			unpriced[from] = true
+		}
+		return v
+	}
+	add := func(account, c string, amount float64) float64 {
+		if amount == 0 {
+			return 0
+		}
+		h := Holding{Account: account, Currency: c, Amount: amount, Value: value(amount, c)}
+		portfolio.Holdings = append(portfolio.Holdings, h)
+		return h.Value
+	}
+
+	for k, v := range balances {
+		portfolio.Exchange += add("exchange", k, v.Available+v.OnOrders)
+	}
+	for k, v := range accounts.Margin {
+		portfolio.Margin += add("margin", k, v)
+	}
+	if pl := summary.ProfitLoss - summary.LendingFees; pl != 0 {
+		// the margin summary is reported in BTC
+		portfolio.MarginPL = value(pl, "BTC")
+	}
+	lending := map[string]float64{}
+	for k, v := range accounts.Lending {
+		lending[k] += v
+	}
+	// offered funds leave the available balance until the offer is taken or cancelled
+	for k, v := range offers {
+		for _, o := range v {
+			lending[k] += o.Amount
+		}
+	}
+	for _, v := range loans.Provided {
+		lending[v.Currency] += v.Amount
+	}
+	for k, v := range lending {
+		portfolio.Lending += add("lending", k, v)
+	}
+	portfolio.Total = portfolio.Exchange + portfolio.Margin + portfolio.MarginPL + portfolio.Lending
+
+	sort.Slice(portfolio.Holdings, func(i, j int) bool {
+		a, b := portfolio.Holdings[i], portfolio.Holdings[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		return a.Currency < b.Currency
+	})
+	for k := range unpriced {
+		portfolio.Unpriced = append(portfolio.Unpriced, k)
+	}
+	sort.Strings(portfolio.Unpriced)
+	return
+}
@@ -0,0 +1,50 @@
+package poloniex
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExamplePrices() {
+	prices := NewPrices(Ticker{
+		"USDT_BTC": {Last: 10000},
+		"BTC_ETH":  {Last: 0.02},
+		"BTC_XMR":  {Last: 0.005},
+	})
+	// there is no USDT_XMR market, so this is routed via BTC
+	v, ok := prices.Convert(10, "XMR", "USDT")
+	fmt.Printf("%.2f %v\n", v, ok)
+	// Output: 500.00 true
+}
+
+func ExamplePoloniex_Portfolio() {
+	p := New("config.json")
+	portfolio, err := p.Portfolio("USDT")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, h := range portfolio.Holdings {
+		fmt.Printf("%-8s %-5s %.8f %.2f\n", h.Account, h.Currency, h.Amount, h.Value)
+	}
+	fmt.Printf("total %.2f\n", portfolio.Total)
+}
+
+func ExamplePortfolio() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	p.Use(answer(map[string]string{
+		"returnTicker":                   `{"USDT_BTC":{"last":"10000"}}`,
+		"returnCompleteBalances":         `{"BTC":{"available":"1","onOrders":"0"}}`,
+		"returnAvailableAccountBalances": `{"lending":{"BTC":"0.5"}}`,
+		"returnMarginAccountSummary":     `{}`,
+		"returnOpenLoanOffers":           `{"BTC":[{"id":7,"rate":"0.0001","amount":"0.25"}]}`,
+		"returnActiveLoans":              `{"provided":[{"id":8,"currency":"BTC","rate":"0.0001","amount":"0.25"}]}`,
+	}))
+	// offered and lent funds count towards the lending account as well as the available balance
+	portfolio, err := p.Portfolio("USDT")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(portfolio.Exchange, portfolio.Lending, portfolio.Total)
+	// Output: 10000 10000 20000
+}
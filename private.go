@@ -1,18 +1,16 @@
 package poloniex
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/url"
-	"strconv"
-	"strings"
 	"time"
 
-	"github.com/franela/goreq"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -50,11 +48,12 @@ type (
 
 	// DepositsWithdrawals holds the history of deposit and withdrawal
 	DepositsWithdrawals struct {
-		Deposits    []deposit
-		Withdrawals []withdrawal
-		Adjustments []adjustment
+		Deposits    []Deposit
+		Withdrawals []Withdrawal
+		Adjustments []Adjustment
 	}
-	deposit struct {
+	// Deposit is a single deposit in DepositsWithdrawals
+	Deposit struct {
 		Currency      string
 		Address       string
 		Amount        float64 `json:",string"`
@@ -63,7 +62,8 @@ type (
 		Timestamp     int64
 		Status        string
 	}
-	withdrawal struct {
+	// Withdrawal is a single withdrawal in DepositsWithdrawals
+	Withdrawal struct {
 		WithdrawalNumber int64 `json:"withdrawalNumber"`
 		Currency         string
 		Address          string
@@ -71,8 +71,8 @@ type (
 		Timestamp        int64
 		Status           string
 	}
-
-	adjustment struct {
+	// Adjustment is a single balance adjustment made by the exchange in DepositsWithdrawals
+	Adjustment struct {
 		Currency  string
 		Amount    float64 `json:",string"`
 		Timestamp int64
@@ -328,14 +328,52 @@ func (p *Poloniex) GenerateNewAddress(currency string) (address string, err erro
 }
 
 // DepositsWithdrawals returns your deposit and withdrawal history for the last 6 months,
-func (p *Poloniex) DepositsWithdrawals() (depositsWithdrawals DepositsWithdrawals, err error) {
+// or between a range specified in UNIX timestamps.
+//
+// If a single date is passed then that is used as the startdate, and current date is used for the enddate.
+// A startdate and enddate may be passed to select a specific period.
+func (p *Poloniex) DepositsWithdrawals(dates ...int64) (depositsWithdrawals DepositsWithdrawals, err error) {
 	params := url.Values{}
-	params.Add("start", fmt.Sprintf("%d", time.Now().Add(-4380*time.Hour).Unix()))
-	params.Add("end", "9999999999")
+	start, end := time.Now().Add(-4380*time.Hour).Unix(), int64(9999999999)
+	if len(dates) > 0 {
+		start = dates[0]
+	}
+	if len(dates) > 1 {
+		end = dates[1]
+	}
+	params.Add("start", fmt.Sprintf("%d", start))
+	params.Add("end", fmt.Sprintf("%d", end))
 	err = p.private("returnDepositsWithdrawals", params, &depositsWithdrawals)
 	return
 }
 
+// DepositsWithdrawalsRange returns your deposit and withdrawal history between start and end,
+// fetched a page of window at a time so long ranges are not asked for in one request. window defaults to 30 days.
+func (p *Poloniex) DepositsWithdrawalsRange(start, end time.Time, window time.Duration) (depositsWithdrawals DepositsWithdrawals, err error) {
+	if window <= 0 {
+		window = 30 * 24 * time.Hour
+	}
+	for from := start; from.Before(end); from = from.Add(window) {
+		to := from.Add(window)
+		if to.After(end) {
+			to = end
+		}
+		// the range is inclusive at both ends, so pages stop a second short of the next
+		last := to.Unix() - 1
+		if !to.Before(end) {
+			last = end.Unix()
+		}
+		page, err := p.DepositsWithdrawals(from.Unix(), last)
+		if err != nil {
+			return depositsWithdrawals, errors.Wrapf(err, "fetching deposits and withdrawals from %s failed", from.Format(timeLayout))
+		}
+		depositsWithdrawals.Deposits = append(depositsWithdrawals.Deposits, page.Deposits...)
+		depositsWithdrawals.Withdrawals = append(depositsWithdrawals.Withdrawals, page.Withdrawals...)
+		depositsWithdrawals.Adjustments = append(depositsWithdrawals.Adjustments, page.Adjustments...)
+	}
+	return
+}
+
 // OpenOrders returns your open orders for a given market
 func (p *Poloniex) OpenOrders(pair string) (openOrders OpenOrders, err error) {
 	params := url.Values{}
@@ -346,9 +384,13 @@ func (p *Poloniex) OpenOrders(pair string) (openOrders OpenOrders, err error) {
 
 // OpenOrdersAll returns your open orders for all markets
 func (p *Poloniex) OpenOrdersAll() (openOrders OpenOrdersAll, err error) {
+	return p.openOrdersAll(context.Background())
+}
+
+func (p *Poloniex) openOrdersAll(ctx context.Context) (openOrders OpenOrdersAll, err error) {
 	params := url.Values{}
 	params.Add("currencyPair", "all")
-	err = p.private("returnOpenOrders", params, &openOrders)
+	err = p.privateContext(ctx, "returnOpenOrders", params, &openOrders)
 	return
 }
 
@@ -384,6 +426,49 @@ func (p *Poloniex) PrivateTradeHistoryAll(dates ...int64) (history PrivateTradeH
 	return
 }
 
+// tradeHistoryLimit is the most trades returnTradeHistory returns in one request
+const tradeHistoryLimit = 10000
+
+// PrivateTradeHistoryRange returns your trades in every market between start and end.
+// The exchange returns at most 10000 trades a request, newest first, so it pages back from end until a page comes back short.
+func (p *Poloniex) PrivateTradeHistoryRange(start, end time.Time) (history PrivateTradeHistoryAll, err error) {
+	history = PrivateTradeHistoryAll{}
+	seen := map[int64]bool{}
+	to := end.Unix()
+	for {
+		params := url.Values{}
+		params.Add("currencyPair", "all")
+		params.Add("start", fmt.Sprintf("%d", start.Unix()))
+		params.Add("end", fmt.Sprintf("%d", to))
+		params.Add("limit", fmt.Sprintf("%d", tradeHistoryLimit))
+		page := PrivateTradeHistoryAll{}
+		if err = p.private("returnTradeHistory", params, &page); err != nil {
+			return history, errors.Wrapf(err, "fetching trade history to %s failed", time.Unix(to, 0).UTC().Format(timeLayout))
+		}
+		n, oldest := 0, to
+		for pair, trades := range page {
+			for _, t := range trades {
+				n++
+				if ts, err := parseTime(t.Date); err == nil && ts.Unix() < oldest {
+					oldest = ts.Unix()
+				}
+				if !seen[t.GlobalTradeID] {
+					seen[t.GlobalTradeID] = true
+					history[pair] = append(history[pair], t)
+				}
+			}
+		}
+		if n < tradeHistoryLimit {
+			return history, nil
+		}
+		if oldest == to {
+			return history, errors.Errorf("more than %d trades at %s, the history cannot be paged", tradeHistoryLimit, time.Unix(to, 0).UTC().Format(timeLayout))
+		}
+		// the end is inclusive, so the oldest second is fetched again and the trades already seen skipped
+		to = oldest
+	}
+}
+
 // OrderTrades returns all trades involving a given order,
 func (p *Poloniex) OrderTrades(orderNumber int64) (ot OrderTrades, err error) {
 	params := url.Values{}
@@ -402,10 +487,14 @@ func (p *Poloniex) OrderStatus(orderNumber int64) (os OrderStatus, err error) {
 
 // CancelOrder cancels an order you have placed in a given market
 func (p *Poloniex) CancelOrder(orderNumber int64) (success bool, err error) {
+	return p.cancelOrder(context.Background(), orderNumber)
+}
+
+func (p *Poloniex) cancelOrder(ctx context.Context, orderNumber int64) (success bool, err error) {
 	params := url.Values{}
 	params.Add("orderNumber", fmt.Sprintf("%d", orderNumber))
 	b := Base{}
-	err = p.private("cancelOrder", params, &b)
+	err = p.privateContext(ctx, "cancelOrder", params, &b)
 	success = b.Success == 1
 	return
 }
@@ -575,6 +664,14 @@ func (p *Poloniex) MarginPosition(pair string) (mp MarginPosition, err error) {
 	return
 }
 
+// MarginPositionAll returns margin position info for all pairs, pairs without a position have a Type of "none"
+func (p *Poloniex) MarginPositionAll() (mp map[string]MarginPosition, err error) {
+	params := url.Values{}
+	params.Add("currencyPair", "all")
+	err = p.private("getMarginPosition", params, &mp)
+	return
+}
+
 // CloseMarginPosition closes a margin position
 func (p *Poloniex) CloseMarginPosition(pair string) (success bool, err error) {
 	params := url.Values{}
@@ -587,12 +684,29 @@ func (p *Poloniex) CloseMarginPosition(pair string) (success bool, err error) {
 
 // Withdraw immediately places a withdrawal for a given currency, with no email confirmation.
 // In order to use this method, withdrawal privilege must be enabled for your API key.
+// If withdrawals are guarded (see GuardWithdrawals) the withdrawal must pass the guard first,
+// and if they are disabled (see DisableWithdrawals) it always fails.
+// Every attempt, allowed or not, is emitted as a "withdrawal-attempt" event.
 func (p *Poloniex) Withdraw(currency string, amount float64, address string) (w Withdraw, err error) {
+	p.haltMutex.RLock()
+	guard, disabled := p.withdrawals, p.noWithdrawals
+	p.haltMutex.RUnlock()
+	if guard != nil && !disabled {
+		return guard.withdraw(currency, amount, address)
+	}
+	var audit func(WithdrawalAttempt)
+	if guard != nil {
+		audit = guard.policy.Audit
+	}
+	return p.withdrawUnguarded(currency, amount, address, disabled, audit)
+}
+
+func (p *Poloniex) withdraw(currency string, amount float64, address string) (w Withdraw, err error) {
 	params := url.Values{}
 	params.Add("currency", currency)
-	params.Add("amount", fmt.Sprintf("%f", amount))
+	params.Add("amount", fmt.Sprintf("%.8f", amount))
 	params.Add("address", address)
-	err = p.private("withdraw", params, w)
+	err = p.private("withdraw", params, &w)
 	return
 }
 
@@ -648,7 +762,6 @@ func (p *Poloniex) TransferBalance(currency string, amount float64, from string,
 	params.Add("amount", toString(amount))
 	params.Add("fromAccount", from)
 	params.Add("toAccount", to)
-	fmt.Printf("%+v", params)
 	err = p.private("transferBalance", params, &tb)
 	return
 }
@@ -731,77 +844,25 @@ func (p *Poloniex) ToggleAutoRenew(orderNumber int64) (success bool, err error)
 	return
 }
 
-//  make a call to the jsonrpc api, marshal into v
+//  make a call to the jsonrpc api through the middleware chain, marshal into v
 func (p *Poloniex) private(method string, params url.Values, retval interface{}) error {
-	if p.debug {
-		defer un(trace("private: " + method))
-	}
+	return p.privateContext(context.Background(), method, params, retval)
+}
 
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
+//  as private, the rate limiter wait gives up when ctx is done
+func (p *Poloniex) privateContext(ctx context.Context, method string, params url.Values, retval interface{}) error {
 	if params == nil {
 		params = url.Values{}
 	}
-	params.Set("nonce", p.getNonce())
 	params.Set("command", method)
-	postData := params.Encode()
-
-	req := goreq.Request{
-		Method:      "POST",
-		Uri:         PRIVATEURI,
-		Body:        postData,
-		ContentType: "application/x-www-form-urlencoded",
-		Accept:      "application/json",
-		Timeout:     130 * time.Second,
-	}
-
-	req.AddHeader("Sign", p.sign(postData))
-	req.AddHeader("Key", p.Key)
-	req.AddHeader("Content-Length", strconv.Itoa(len(postData)))
-
-	res, err := req.Do()
-	if err != nil {
-		return err
-	}
-
-	defer res.Body.Close()
-
-	s, err := res.Body.ToString()
-	if err != nil {
-		return err
-	}
-
-	if p.debug {
-		fmt.Println(s)
-	}
-
-	if strings.HasPrefix(s, "[") {
-		// TODO: fix this shit
-		//  poloniex only ever returns an array type when there is no real data
-		//  e.g. no data in a time range
-		//  if this ever changes then this breaks badly
-		return nil
-	}
-
-	//  do we have an error message from the server?
-	perr := Error{}
-	err = json.Unmarshal([]byte(s), &perr)
-	if err == nil && perr.Error != "" {
-		//  looks like we have an error from poloniex
-		return fmt.Errorf(perr.Error)
-	}
-
-	err = json.Unmarshal([]byte(s), retval)
-	if err != nil && retval == nil {
-		log.Println(err)
-		return err
-	}
+	req := &Request{Context: ctx, Private: true, Command: method, Params: params, Result: retval}
+	_, err := p.privateChain()(req)
 	return err
 }
 
 //  generate hmac-sha512 hash, hex encoded
-func (p *Poloniex) sign(payload string) string {
-	mac := hmac.New(sha512.New, []byte(p.Secret))
+func sign(secret, payload string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
 	mac.Write([]byte(payload))
 	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -1,13 +1,10 @@
 package poloniex
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/url"
 	"time"
-
-	"github.com/franela/goreq"
-	"github.com/k0kubun/pp"
 )
 
 type (
@@ -277,35 +274,13 @@ func tempToOrderBook(obt OrderBookTemp) (ob OrderBook) {
 	return
 }
 
-// public calls a public endpoint
-func (p *Poloniex) public(command string, params url.Values, retval interface{}) (err error) {
-	if p.debug {
-		defer un(trace("public: " + command))
-	}
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
+// public calls a public endpoint through the middleware chain, see Use
+func (p *Poloniex) public(command string, params url.Values, retval interface{}) error {
 	if params == nil {
 		params = url.Values{}
 	}
 	params.Add("command", command)
-	req := goreq.Request{Uri: PUBLICURI, QueryString: params, Timeout: 130 * time.Second}
-	res, err := req.Do()
-	if err != nil {
-		return
-	}
-	if p.debug {
-		pp.Println(res.Request.URL.String())
-	}
-
-	defer res.Body.Close()
-
-	s, err := res.Body.ToString()
-	if err != nil {
-		return
-	}
-	if p.debug {
-		pp.Println(s)
-	}
-	err = json.Unmarshal([]byte(s), retval)
-	return
+	req := &Request{Context: context.Background(), Command: command, Params: params, Result: retval}
+	_, err := p.publicChain()(req)
+	return err
 }
@@ -0,0 +1,236 @@
+package poloniex
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ReplayMaxSpeed replays a recording as fast as the handlers allow
+	ReplayMaxSpeed = 0.0
+	// ReplayOriginalSpeed replays a recording with the original gaps between frames
+	ReplayOriginalSpeed = 1.0
+
+	recordingVersion = 1
+)
+
+type (
+	// Recorder captures raw websocket frames, with their receive timestamps, to a gzip compressed stream
+	Recorder struct {
+		mutex  sync.Mutex
+		gz     *gzip.Writer
+		enc    *json.Encoder
+		closer io.Closer
+		closed bool
+	}
+
+	// Replayer reads a recording made by a Recorder
+	Replayer struct {
+		gz     *gzip.Reader
+		dec    *json.Decoder
+		closer io.Closer
+		header recordingHeader
+	}
+
+	// RecordedFrame is a single raw websocket frame, exactly as received, and the time it was received
+	RecordedFrame struct {
+		TS    time.Time `json:"ts"`
+		Frame string    `json:"frame"`
+	}
+
+	// recordingHeader is the first line of every recording, it carries the market ids needed to parse the frames
+	recordingHeader struct {
+		Version int               `json:"version"`
+		Started time.Time         `json:"started"`
+		Markets map[string]string `json:"markets"`
+	}
+)
+
+// NewRecorder starts a recording on w, markets is the id to pair lookup in use when the frames are received (usually p.ByID)
+func NewRecorder(w io.Writer, markets map[string]string) (*Recorder, error) {
+	gz := gzip.NewWriter(w)
+	r := &Recorder{gz: gz, enc: json.NewEncoder(gz)}
+	h := recordingHeader{Version: recordingVersion, Started: time.Now(), Markets: markets}
+	if err := r.enc.Encode(h); err != nil {
+		return nil, errors.Wrap(err, "writing recording header failed")
+	}
+	return r, nil
+}
+
+// NewFileRecorder creates (or truncates) filename and starts a recording in it
+func NewFileRecorder(filename string, markets map[string]string) (*Recorder, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating "+filename+" failed")
+	}
+	r, err := NewRecorder(f, markets)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Record appends a single frame to the recording, frames recorded after Close are dropped
+func (r *Recorder) Record(ts time.Time, frame []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return nil
+	}
+	return r.enc.Encode(RecordedFrame{TS: ts, Frame: string(frame)})
+}
+
+// Flush pushes any buffered frames to the underlying writer
+func (r *Recorder) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.gz.Flush()
+}
+
+// Close finishes the recording, closing the file if the recorder owns one
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.gz.Close()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Record attaches a recorder to the websocket loop started by StartWS, pass nil to stop recording
+func (p *Poloniex) Record(r *Recorder) {
+	p.recMutex.Lock()
+	defer p.recMutex.Unlock()
+	p.recorder = r
+}
+
+func (p *Poloniex) record(ts time.Time, frame []byte) error {
+	p.recMutex.Lock()
+	r := p.recorder
+	p.recMutex.Unlock()
+	if r == nil {
+		return nil
+	}
+	return r.Record(ts, frame)
+}
+
+// NewReplayer opens a recording made by a Recorder
+func NewReplayer(rd io.Reader) (*Replayer, error) {
+	gz, err := gzip.NewReader(rd)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening recording failed")
+	}
+	r := &Replayer{gz: gz, dec: json.NewDecoder(gz)}
+	if err := r.dec.Decode(&r.header); err != nil {
+		return nil, errors.Wrap(err, "reading recording header failed")
+	}
+	if r.header.Version != recordingVersion {
+		return nil, errors.Errorf("unsupported recording version %d", r.header.Version)
+	}
+	return r, nil
+}
+
+// OpenReplayer opens the recording stored in filename
+func OpenReplayer(filename string) (*Replayer, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening "+filename+" failed")
+	}
+	r, err := NewReplayer(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Markets returns the market id to pair lookup that was in use when the recording was made
+func (r *Replayer) Markets() map[string]string {
+	return r.header.Markets
+}
+
+// Started returns the time the recording was started
+func (r *Replayer) Started() time.Time {
+	return r.header.Started
+}
+
+// Client returns an offline client set up with the markets from the recording,
+// it makes no network calls and can be used to replay the recording without a connection
+func (r *Replayer) Client(options ...Option) *Poloniex {
+	p := newClient(options...)
+	p.setMarkets(r.header.Markets)
+	return p
+}
+
+// Next returns the next frame in the recording, io.EOF is returned at the end of the recording
+func (r *Replayer) Next() (rf RecordedFrame, err error) {
+	err = r.dec.Decode(&rf)
+	return
+}
+
+// Close closes the recording, closing the file if the replayer owns one
+func (r *Replayer) Close() error {
+	err := r.gz.Close()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Replay feeds the frames of a recording through the same parse and emit pipeline as StartWS.
+// speed scales the original gaps between frames, ReplayOriginalSpeed keeps them as recorded,
+// 10 plays ten times faster and ReplayMaxSpeed does not wait at all.
+// Frames which fail to parse are skipped, as they are on a live connection, and emitted as a "replay-error" event.
+func (p *Poloniex) Replay(ctx context.Context, r *Replayer, speed float64) error {
+	var first time.Time
+	start := time.Now()
+	for {
+		rf, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "reading recorded frame failed")
+		}
+		if first.IsZero() {
+			first = rf.TS
+		}
+		if speed > 0 {
+			due := time.Duration(float64(rf.TS.Sub(first)) / speed)
+			if wait := due - time.Since(start); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if err := p.handleFrame(rf.TS, []byte(rf.Frame)); err != nil && err != ErrAck {
+			p.Emit("replay-error", errors.Wrapf(err, "replaying frame recorded at %s failed", rf.TS.Format(time.RFC3339Nano)))
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package poloniex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+func ExampleReplayer() {
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, map[string]string{"121": "USDT_BTC"})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	ts := time.Unix(1500000000, 0).UTC()
+	rec.Record(ts, []byte(`[121,1,[["o",1,"7000.00000000","0.50000000"]]]`))
+	rec.Record(ts.Add(time.Second), []byte(`[121,2,[["t","1",0,"7001.00000000","0.10000000",1500000001]]]`))
+	rec.Record(ts.Add(2*time.Second), []byte(`not json`))
+	rec.Close()
+
+	rep, err := NewReplayer(buf)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer rep.Close()
+	p := rep.Client()
+	p.On("USDT_BTC", func(m WSOrderbook) {
+		fmt.Println(m.Event, m.Type, m.Rate, m.Amount)
+	})
+	p.On("replay-error", func(err error) {
+		fmt.Println(err)
+	})
+	if err := p.Replay(context.Background(), rep, ReplayMaxSpeed); err != nil {
+		log.Fatalln(err)
+	}
+	// Output:
+	// modify bid 7000 0.5
+	// trade sell 7001 0.1
+	// replaying frame recorded at 2017-07-14T02:40:02Z failed: invalid character 'o' in literal null (expecting 'u')
+}
@@ -0,0 +1,265 @@
+package poloniex
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TaxFormat selects the layout of a tax report CSV export
+type TaxFormat int
+
+const (
+	// TaxFormatDisposals writes one line per disposal, in the style of a capital gains schedule, followed by income lines
+	TaxFormatDisposals TaxFormat = iota
+	// TaxFormatKoinly writes every trade and income line in the Koinly universal import format
+	TaxFormatKoinly
+	// TaxFormatCoinTracking writes every trade and income line in the CoinTracking CSV import format
+	TaxFormatCoinTracking
+)
+
+type (
+	// TaxReport holds the capital gains and income for a single calendar year (UTC), from exchange trades only.
+	// Disposals are valued in Currency as described on PnL, converting that to a fiat currency is left to the tax tool.
+	// Income and Trades are in the currencies received and sent.
+	TaxReport struct {
+		Year      int
+		Method    LotMethod
+		Currency  string
+		Disposals []Disposal
+		Income    []IncomeLine
+		Trades    []TaxTrade
+		// Unpriced lists currencies traded when there was no route to Currency, these are valued at zero
+		Unpriced []string
+	}
+
+	// IncomeLine is income which is not a disposal, such as lending interest
+	IncomeLine struct {
+		Date        time.Time
+		Currency    string
+		Amount      float64
+		Kind        string
+		Description string
+	}
+
+	// TaxTrade is a single trade expressed as currency sent and received
+	TaxTrade struct {
+		Date             time.Time
+		Pair             string
+		Type             string
+		SentAmount       float64
+		SentCurrency     string
+		ReceivedAmount   float64
+		ReceivedCurrency string
+		FeeAmount        float64
+		FeeCurrency      string
+		TradeID          int64
+	}
+)
+
+// NewTaxReport builds the report for year from your complete account history.
+// The trade history must reach back far enough to cover the acquisition of everything disposed of in year.
+// Margin trades are left out of both Disposals and Trades, as they are made with borrowed funds.
+func NewTaxReport(year int, method LotMethod, history PrivateTradeHistoryAll, dw DepositsWithdrawals, lending LendingHistory) (report TaxReport, err error) {
+	report = TaxReport{Year: year, Method: method}
+	inYear := func(t time.Time) bool { return t.UTC().Year() == year }
+
+	pl := NewPnL(method)
+	if err = pl.AddTrades(history); err != nil {
+		return
+	}
+	pl.AddDepositsWithdrawals(dw)
+	pnl := pl.Report(nil)
+	report.Currency, report.Unpriced = pnl.Currency, pnl.Unpriced
+	for _, d := range pnl.Disposals {
+		if inYear(d.Disposed) {
+			report.Disposals = append(report.Disposals, d)
+		}
+	}
+
+	for pair, trades := range history {
+		base, quote := splitPair(pair)
+		for _, v := range trades {
+			ts, err := parseTime(v.Date)
+			if err != nil {
+				return report, errors.Wrap(err, "parsing trade date failed")
+			}
+			if !inYear(ts) || (v.Category != "exchange" && v.Category != "") {
+				continue
+			}
+			t := TaxTrade{Date: ts, Pair: pair, Type: v.Type, TradeID: v.GlobalTradeID}
+			if v.Type == "buy" {
+				t.FeeAmount, t.FeeCurrency = v.Amount*v.Fee, quote
+				t.SentAmount, t.SentCurrency = v.Total, base
+				t.ReceivedAmount, t.ReceivedCurrency = v.Amount-t.FeeAmount, quote
+			} else {
+				t.FeeAmount, t.FeeCurrency = v.Total*v.Fee, base
+				t.SentAmount, t.SentCurrency = v.Amount, quote
+				t.ReceivedAmount, t.ReceivedCurrency = v.Total-t.FeeAmount, base
+			}
+			report.Trades = append(report.Trades, t)
+		}
+	}
+	sort.SliceStable(report.Trades, func(i, j int) bool {
+		if report.Trades[i].Date.Equal(report.Trades[j].Date) {
+			return report.Trades[i].TradeID < report.Trades[j].TradeID
+		}
+		return report.Trades[i].Date.Before(report.Trades[j].Date)
+	})
+
+	for _, v := range lending {
+		ts, err := parseTime(v.Close)
+		if err != nil {
+			return report, errors.Wrap(err, "parsing lending close date failed")
+		}
+		if inYear(ts) && v.Earned != 0 {
+			report.Income = append(report.Income, IncomeLine{
+				Date: ts, Currency: v.Currency, Amount: v.Earned, Kind: "lending",
+				Description: fmt.Sprintf("loan %d at %.8f for %.4f days", v.ID, v.Rate, v.Duration),
+			})
+		}
+	}
+	for _, v := range dw.Adjustments {
+		ts := time.Unix(v.Timestamp, 0).UTC()
+		if inYear(ts) && isComplete(v.Status) && v.Amount != 0 {
+			report.Income = append(report.Income, IncomeLine{
+				Date: ts, Currency: v.Currency, Amount: v.Amount, Kind: "adjustment", Description: v.Title,
+			})
+		}
+	}
+	sort.SliceStable(report.Income, func(i, j int) bool { return report.Income[i].Date.Before(report.Income[j].Date) })
+	return
+}
+
+// historyStart is before the exchange opened, so before anything in any account's history
+var historyStart = time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TaxReport fetches your account history up to the end of year and builds the report for it.
+// Every trade, deposit and withdrawal since the exchange opened is fetched, so lots bought in earlier years have their cost.
+func (p *Poloniex) TaxReport(year int, method LotMethod) (report TaxReport, err error) {
+	end := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	history, err := p.PrivateTradeHistoryRange(historyStart, end)
+	if err != nil {
+		return report, errors.Wrap(err, "fetching trade history failed")
+	}
+	dw, err := p.DepositsWithdrawalsRange(historyStart, end, 0)
+	if err != nil {
+		return report, errors.Wrap(err, "fetching deposits and withdrawals failed")
+	}
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	lending, err := p.LendingHistory(start.Unix(), end.Unix(), 0)
+	if err != nil {
+		return report, errors.Wrap(err, "fetching lending history failed")
+	}
+	return NewTaxReport(year, method, history, dw, lending)
+}
+
+// Gain totals the gains of all disposals, keyed by the currency they are in
+func (r TaxReport) Gain() map[string]float64 {
+	gain := map[string]float64{}
+	for _, d := range r.Disposals {
+		gain[d.CostCurrency] += d.Gain
+	}
+	return gain
+}
+
+// WriteCSV exports the report in the format given
+func (r TaxReport) WriteCSV(w io.Writer, format TaxFormat) error {
+	cw := csv.NewWriter(w)
+	var rows [][]string
+	switch format {
+	case TaxFormatDisposals:
+		rows = r.disposalRows()
+	case TaxFormatKoinly:
+		rows = r.koinlyRows()
+	case TaxFormatCoinTracking:
+		rows = r.coinTrackingRows()
+	default:
+		return errors.Errorf("unknown tax format %d", format)
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return errors.Wrap(err, "writing tax report failed")
+	}
+	return nil
+}
+
+func csvAmount(f float64) string {
+	return fmt.Sprintf("%.8f", f)
+}
+
+func (r TaxReport) disposalRows() [][]string {
+	rows := [][]string{{"Description", "Date Acquired", "Date Sold", "Proceeds", "Cost Basis", "Gain", "Currency", "Type"}}
+	for _, d := range r.Disposals {
+		acquired := "UNKNOWN"
+		if !d.Acquired.IsZero() {
+			acquired = d.Acquired.UTC().Format("2006-01-02")
+		}
+		typ := "disposal"
+		if d.Deposited {
+			typ = "disposal (deposited, no cost basis)"
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%s %s via %s", csvAmount(d.Amount), d.Currency, d.Pair),
+			acquired, d.Disposed.UTC().Format("2006-01-02"),
+			csvAmount(d.Proceeds), csvAmount(d.Cost), csvAmount(d.Gain), d.CostCurrency, typ,
+		})
+	}
+	for _, v := range r.Income {
+		rows = append(rows, []string{
+			v.Description, "", v.Date.UTC().Format("2006-01-02"), csvAmount(v.Amount), "", csvAmount(v.Amount), v.Currency, v.Kind,
+		})
+	}
+	return rows
+}
+
+func (r TaxReport) koinlyRows() [][]string {
+	rows := [][]string{{"Date", "Sent Amount", "Sent Currency", "Received Amount", "Received Currency",
+		"Fee Amount", "Fee Currency", "Net Worth Amount", "Net Worth Currency", "Label", "Description", "TxHash"}}
+	date := func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") }
+	for _, t := range r.Trades {
+		rows = append(rows, []string{
+			date(t.Date), csvAmount(t.SentAmount), t.SentCurrency, csvAmount(t.ReceivedAmount), t.ReceivedCurrency,
+			csvAmount(t.FeeAmount), t.FeeCurrency, "", "", "", fmt.Sprintf("poloniex %s %s", t.Pair, t.Type), fmt.Sprintf("%d", t.TradeID),
+		})
+	}
+	for _, v := range r.Income {
+		label := "income"
+		if v.Kind == "lending" {
+			label = "lending interest"
+		}
+		row := []string{date(v.Date), "", "", csvAmount(v.Amount), v.Currency, "", "", "", "", label, v.Description, ""}
+		if v.Amount < 0 {
+			row = []string{date(v.Date), csvAmount(-v.Amount), v.Currency, "", "", "", "", "", "", "", v.Description, ""}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r TaxReport) coinTrackingRows() [][]string {
+	rows := [][]string{{"Type", "Buy Amount", "Buy Currency", "Sell Amount", "Sell Currency",
+		"Fee", "Fee Currency", "Exchange", "Trade-Group", "Comment", "Date"}}
+	date := func(t time.Time) string { return t.UTC().Format(timeLayout) }
+	for _, t := range r.Trades {
+		rows = append(rows, []string{
+			"Trade", csvAmount(t.ReceivedAmount), t.ReceivedCurrency, csvAmount(t.SentAmount), t.SentCurrency,
+			csvAmount(t.FeeAmount), t.FeeCurrency, "Poloniex", "", fmt.Sprintf("%s %d", t.Pair, t.TradeID), date(t.Date),
+		})
+	}
+	for _, v := range r.Income {
+		typ := "Income"
+		if v.Kind == "lending" {
+			typ = "Lending Income"
+		}
+		row := []string{typ, csvAmount(v.Amount), v.Currency, "", "", "", "", "Poloniex", "", v.Description, date(v.Date)}
+		if v.Amount < 0 {
+			row = []string{"Other Fee", "", "", csvAmount(-v.Amount), v.Currency, "", "", "Poloniex", "", v.Description, date(v.Date)}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,89 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+func ExampleTaxReport() {
+	history := PrivateTradeHistoryAll{
+		"USDT_BTC": {
+			{Date: "2018-03-01 12:00:00", Type: "buy", Rate: 10000, Amount: 1, Total: 10000, Category: "exchange", GlobalTradeID: 1},
+			{Date: "2019-05-01 12:00:00", Type: "sell", Rate: 6000, Amount: 0.5, Total: 3000, Category: "exchange", GlobalTradeID: 2},
+		},
+	}
+	lending := LendingHistory{
+		{ID: 7, Currency: "USDT", Rate: 0.0002, Amount: 1000, Duration: 2, Earned: 0.4, Close: "2019-07-03 00:00:00"},
+	}
+	report, err := NewTaxReport(2019, FIFO, history, DepositsWithdrawals{}, lending)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	report.WriteCSV(os.Stdout, TaxFormatDisposals)
+	// Output:
+	// Description,Date Acquired,Date Sold,Proceeds,Cost Basis,Gain,Currency,Type
+	// 0.50000000 BTC via USDT_BTC,2018-03-01,2019-05-01,3000.00000000,5000.00000000,-2000.00000000,USDT,disposal
+	// loan 7 at 0.00020000 for 2.0000 days,,2019-07-03,0.40000000,,0.40000000,USDT,lending
+}
+
+func ExampleNewTaxReport() {
+	// USDT to BTC to ETH and back to USDT, the margin trade is left out
+	history := PrivateTradeHistoryAll{
+		"USDT_BTC": {
+			{Date: "2018-12-01 12:00:00", Type: "buy", Rate: 4000, Amount: 1, Total: 4000, Category: "exchange", GlobalTradeID: 1},
+			{Date: "2019-03-01 12:00:00", Type: "sell", Rate: 4200, Amount: 2, Total: 8400, Category: "marginTrade", GlobalTradeID: 4},
+		},
+		"BTC_ETH":  {{Date: "2019-02-01 12:00:00", Type: "buy", Rate: 0.02, Amount: 50, Total: 1, Category: "exchange", GlobalTradeID: 2}},
+		"USDT_ETH": {{Date: "2019-04-01 12:00:00", Type: "sell", Rate: 100, Amount: 50, Total: 5000, Category: "exchange", GlobalTradeID: 3}},
+	}
+	report, err := NewTaxReport(2019, FIFO, history, DepositsWithdrawals{}, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	report.WriteCSV(os.Stdout, TaxFormatDisposals)
+	fmt.Println(len(report.Trades), "trades", report.Gain())
+	// Output:
+	// Description,Date Acquired,Date Sold,Proceeds,Cost Basis,Gain,Currency,Type
+	// 1.00000000 BTC via BTC_ETH,2018-12-01,2019-02-01,4000.00000000,4000.00000000,0.00000000,USDT,disposal
+	// 50.00000000 ETH via USDT_ETH,2019-02-01,2019-04-01,5000.00000000,4000.00000000,1000.00000000,USDT,disposal
+	// 2 trades map[USDT:1000]
+}
+
+func ExamplePoloniex_PrivateTradeHistoryRange() {
+	// 12000 trades, two a second, more than one request returns
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := PrivateTradeHistory{}
+	for i := 0; i < 12000; i++ {
+		date := t0.Add(time.Duration(i/2) * time.Second).Format(timeLayout)
+		trades = append(trades, PrivateTradeHistoryEntry{Date: date, Type: "buy", GlobalTradeID: int64(i + 1)})
+	}
+	p := NewREST("key", "secret")
+	requests := 0
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			requests++
+			start, _ := strconv.ParseInt(req.Params.Get("start"), 10, 64)
+			end, _ := strconv.ParseInt(req.Params.Get("end"), 10, 64)
+			limit, _ := strconv.Atoi(req.Params.Get("limit"))
+			page := PrivateTradeHistory{}
+			for i := len(trades) - 1; i >= 0 && len(page) < limit; i-- {
+				ts, _ := parseTime(trades[i].Date)
+				if ts.Unix() >= start && ts.Unix() <= end {
+					page = append(page, trades[i])
+				}
+			}
+			b, err := json.Marshal(PrivateTradeHistoryAll{"USDT_BTC": page})
+			return &Response{Status: 200, Body: string(b)}, err
+		}
+	})
+	history, err := p.PrivateTradeHistoryRange(t0, t0.Add(24*time.Hour))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(len(history["USDT_BTC"]), "trades in", requests, "requests")
+	// Output: 12000 trades in 2 requests
+}
@@ -0,0 +1,53 @@
+package poloniex
+
+import (
+	"context"
+)
+
+type (
+	// Tracer starts spans around the client's REST requests and websocket messages.
+	// It has the shape of an OpenTelemetry tracer, so a thin adapter lets the client report to one
+	// without depending on it. The attributes set are command, pair, channel, attempt, attempts and http.status_code.
+	Tracer interface {
+		// Start starts a span, a child of any span in ctx, returning a context holding it
+		Start(ctx context.Context, name string) (context.Context, Span)
+	}
+
+	// Span is an operation being traced
+	Span interface {
+		SetAttribute(key string, value interface{})
+		// RecordError marks the span as failed
+		RecordError(err error)
+		End()
+	}
+
+	// nopTracer traces nothing, it is the default
+	nopTracer struct{}
+	nopSpan   struct{}
+)
+
+// WithTracer makes the client trace through t, by default nothing is traced
+func WithTracer(t Tracer) Option {
+	return func(p *Poloniex) {
+		if t == nil {
+			t = nopTracer{}
+		}
+		p.tracer = t
+	}
+}
+
+func (nopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+func (nopSpan) RecordError(err error)                      {}
+func (nopSpan) End()                                       {}
+
+// endSpan ends a span, recording err unless it is nil or a dry run
+func endSpan(span Span, err error) {
+	if err != nil && err != ErrDryRun {
+		span.RecordError(err)
+	}
+	span.End()
+}
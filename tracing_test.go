@@ -0,0 +1,62 @@
+package poloniex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+)
+
+// printTracer prints each span as it ends
+type (
+	printTracer struct{}
+	printSpan   struct {
+		name  string
+		attrs []string
+	}
+)
+
+func (printTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, &printSpan{name: name}
+}
+
+func (s *printSpan) SetAttribute(key string, value interface{}) {
+	s.attrs = append(s.attrs, fmt.Sprintf("%s=%v", key, value))
+}
+
+func (s *printSpan) RecordError(err error) {
+	s.attrs = append(s.attrs, "error="+err.Error())
+}
+
+func (s *printSpan) End() {
+	fmt.Println(s.name, strings.Join(s.attrs, " "))
+}
+
+func ExampleWithTracer() {
+	p := NewREST("key", "secret", WithTracer(printTracer{}))
+	p.DryRun(ioutil.Discard)
+	p.Ticker()
+	p.OpenOrders("USDT_BTC")
+
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, map[string]string{"121": "USDT_BTC"})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	rec.Record(time.Unix(1500000000, 0), []byte(`[121,1,[["o",1,"7000.00000000","0.50000000"]]]`))
+	rec.Close()
+	rep, err := NewReplayer(buf)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer rep.Close()
+	rep.Client(WithTracer(printTracer{})).Replay(context.Background(), rep, ReplayMaxSpeed)
+	// Output:
+	// poloniex.public command=returnTicker
+	// poloniex.private.request command=returnOpenOrders attempt=1
+	// poloniex.private command=returnOpenOrders pair=USDT_BTC attempts=1
+	// poloniex.ws channel=USDT_BTC pair=USDT_BTC
+}
@@ -0,0 +1,403 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TriggerKind is the kind of condition a Trigger waits for
+type TriggerKind string
+
+const (
+	// StopLoss fires when the price moves against the position: down through Price for a sell, up through it for a buy
+	StopLoss TriggerKind = "stop-loss"
+	// TakeProfit fires when the price moves in favour of the position: up through Price for a sell, down through it for a buy
+	TakeProfit TriggerKind = "take-profit"
+	// TrailingStop is a stop loss which follows the best price seen at a distance of Trail
+	TrailingStop TriggerKind = "trailing-stop"
+)
+
+// TrailingSaveInterval is the least time between writes of the triggers file for trailing stop moves,
+// which can happen on every price. A stop which moves in between is written with the next change, or on Stop.
+var TrailingSaveInterval = 10 * time.Second
+
+// TriggerRetryInterval is how long a trigger whose order was certainly not placed, for example while trading is halted,
+// waits before firing again. The wait doubles with each failure, up to TriggerMaxRetryInterval.
+var (
+	TriggerRetryInterval    = 5 * time.Second
+	TriggerMaxRetryInterval = 5 * time.Minute
+)
+
+type (
+	// Trigger is an order held client side until its condition is met
+	Trigger struct {
+		ID     string
+		Pair   string
+		Side   string
+		Kind   TriggerKind
+		Amount float64
+		// Price is the trigger price, for trailing stops it is moved as the best price improves
+		Price float64
+		// Trail is the distance of a trailing stop from the best price, as a fraction (0.05 is 5%)
+		Trail float64
+		// Best is the best price seen by a trailing stop
+		Best float64
+		// Rate is the limit rate of the order placed, when zero the trigger price moved by Slippage is used
+		Rate     float64
+		Slippage float64
+		// ImmediateOrCancel places the order as immediate-or-cancel so nothing is left on the book
+		ImmediateOrCancel bool
+		// OCO is the ID of a trigger which is cancelled when this one fires (one-cancels-other)
+		OCO     string
+		Created time.Time
+		// firing is set while the order is being placed, so the trigger is not fired twice
+		firing bool
+		// failures counts the attempts which were certainly not placed, the trigger does not fire again before retry
+		failures int
+		retry    time.Time
+	}
+
+	// TriggerResult is emitted as a "trigger-fired" event when a trigger fires
+	TriggerResult struct {
+		Trigger     Trigger
+		Price       float64
+		Rate        float64
+		OrderNumber int64
+		Trades      []ResultingTrade
+		Err         error
+		// Failed is set when placing failed in a way which may still have placed the order, such as a timeout.
+		// The trigger and its OCO are removed rather than risk a second order, check the open orders and trades.
+		// Otherwise a trigger whose order was not placed stays pending and fires again, see TriggerRetryInterval.
+		Failed bool
+	}
+
+	// Triggers watches the websocket ticker and trade streams and places orders when triggers fire.
+	// Pending triggers are saved to a file, if one is given, so they survive restarts.
+	// A trigger whose order was certainly not placed, because trading is halted or in dry run, stays pending
+	// and fires again after a backoff. Any other failure removes it, see TriggerResult.Failed.
+	Triggers struct {
+		p        *Poloniex
+		mutex    sync.Mutex
+		filename string
+		seq      int64
+		triggers map[string]*Trigger
+		remove   []func()
+		// saved is when the file was last written, and dirty is set while trailing stop moves are waiting to be written
+		saved time.Time
+		dirty bool
+	}
+
+	triggersFile struct {
+		Seq      int64
+		Triggers []*Trigger
+	}
+)
+
+// NewTriggers creates a trigger engine for the client, loading any pending triggers from filename.
+// Pass an empty filename to keep triggers in memory only.
+func NewTriggers(p *Poloniex, filename string) (*Triggers, error) {
+	e := &Triggers{p: p, filename: filename, triggers: map[string]*Trigger{}}
+	if filename == "" {
+		return e, nil
+	}
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading "+filename+" failed")
+	}
+	tf := triggersFile{}
+	if err := json.Unmarshal(b, &tf); err != nil {
+		return nil, errors.Wrap(err, "unmarshal of triggers failed")
+	}
+	e.seq = tf.Seq
+	for _, t := range tf.Triggers {
+		e.triggers[t.ID] = t
+	}
+	return e, nil
+}
+
+// Start listens to ticker and trade events, the pairs watched must be subscribed to
+func (e *Triggers) Start() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.remove == nil {
+		e.remove = []func(){e.p.listen("ticker", e.onTicker), e.p.listen("trade", e.onTrade)}
+	}
+}
+
+// Stop stops listening, pending triggers are kept and saved
+func (e *Triggers) Stop() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, remove := range e.remove {
+		remove()
+	}
+	e.remove = nil
+	if !e.dirty {
+		return nil
+	}
+	return e.save()
+}
+
+// Add adds a trigger, returning its ID
+func (e *Triggers) Add(t Trigger) (string, error) {
+	if t.Side != "buy" && t.Side != "sell" {
+		return "", errors.New("trigger side must be buy or sell")
+	}
+	if t.Amount <= 0 {
+		return "", errors.New("trigger amount must be positive")
+	}
+	switch t.Kind {
+	case StopLoss, TakeProfit:
+		if t.Price <= 0 {
+			return "", errors.New("trigger price must be positive")
+		}
+	case TrailingStop:
+		if t.Trail <= 0 || t.Trail >= 1 {
+			return "", errors.New("trailing stop distance must be between 0 and 1")
+		}
+	default:
+		return "", errors.Errorf("unknown trigger kind %q", t.Kind)
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.seq++
+	t.ID = fmt.Sprintf("%d", e.seq)
+	t.Created = time.Now()
+	e.triggers[t.ID] = &t
+	return t.ID, e.save()
+}
+
+// StopLoss adds a stop loss trigger
+func (e *Triggers) StopLoss(pair, side string, price, amount float64) (string, error) {
+	return e.Add(Trigger{Pair: pair, Side: side, Kind: StopLoss, Price: price, Amount: amount})
+}
+
+// TakeProfit adds a take profit trigger
+func (e *Triggers) TakeProfit(pair, side string, price, amount float64) (string, error) {
+	return e.Add(Trigger{Pair: pair, Side: side, Kind: TakeProfit, Price: price, Amount: amount})
+}
+
+// TrailingStop adds a trailing stop trigger, trail is the distance from the best price as a fraction
+func (e *Triggers) TrailingStop(pair, side string, trail, amount float64) (string, error) {
+	return e.Add(Trigger{Pair: pair, Side: side, Kind: TrailingStop, Trail: trail, Amount: amount})
+}
+
+// OCO adds two triggers which cancel each other, typically a stop loss and a take profit for the same position
+func (e *Triggers) OCO(a, b Trigger) (string, string, error) {
+	ida, err := e.Add(a)
+	if err != nil {
+		return "", "", err
+	}
+	idb, err := e.Add(b)
+	if err != nil {
+		e.Cancel(ida)
+		return "", "", err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.triggers[ida].OCO = idb
+	e.triggers[idb].OCO = ida
+	return ida, idb, e.save()
+}
+
+// Cancel removes a pending trigger
+func (e *Triggers) Cancel(id string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if _, ok := e.triggers[id]; !ok {
+		return errors.New("unknown trigger " + id)
+	}
+	delete(e.triggers, id)
+	return e.save()
+}
+
+// Pending returns the pending triggers, oldest first
+func (e *Triggers) Pending() []Trigger {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	pending := make([]Trigger, 0, len(e.triggers))
+	for _, t := range e.triggers {
+		pending = append(pending, *t)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Created.Before(pending[j].Created) })
+	return pending
+}
+
+func (e *Triggers) onTicker(m WSTicker) {
+	e.Check(m.Pair, m.Last)
+}
+
+func (e *Triggers) onTrade(m WSOrderbook) {
+	e.Check(m.Pair, m.Rate)
+}
+
+// Check evaluates the triggers of a pair against a price, firing any whose condition is met
+func (e *Triggers) Check(pair string, price float64) {
+	if price <= 0 {
+		return
+	}
+	e.mutex.Lock()
+	now := time.Now()
+	fired := []Trigger{}
+	for _, t := range e.triggers {
+		if t.Pair != pair || t.firing {
+			continue
+		}
+		if t.Kind == TrailingStop && t.follow(price) {
+			e.dirty = true
+		}
+		if oco, ok := e.triggers[t.OCO]; ok && oco.firing {
+			continue
+		}
+		if t.crossed(price) && !now.Before(t.retry) {
+			t.firing = true
+			fired = append(fired, *t)
+		}
+	}
+	var err error
+	if e.dirty && time.Since(e.saved) >= TrailingSaveInterval {
+		err = e.save()
+	}
+	e.mutex.Unlock()
+
+	if err != nil {
+		e.p.Emit("trigger-error", err)
+	}
+	for _, t := range fired {
+		r := e.fire(t, price)
+		r.Failed = r.Err != nil && !notPlaced(r.Err)
+		if err := e.fired(t, r); err != nil {
+			e.p.Emit("trigger-error", err)
+		}
+		e.p.Emit("trigger-fired", r)
+	}
+}
+
+// fired removes a trigger, and any other of its OCO pair, once its order is placed or may have been.
+// When the order was certainly not placed the trigger is left pending to fire again after a backoff.
+func (e *Triggers) fired(t Trigger, r TriggerResult) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if r.Err != nil && !r.Failed {
+		if p, ok := e.triggers[t.ID]; ok {
+			p.firing = false
+			p.failures++
+			p.retry = time.Now().Add(retryBackoff(p.failures))
+		}
+		return nil
+	}
+	delete(e.triggers, t.ID)
+	delete(e.triggers, t.OCO)
+	return e.save()
+}
+
+// notPlaced reports whether err means an order certainly did not reach the exchange
+func notPlaced(err error) bool {
+	switch errors.Cause(err) {
+	case ErrHalted, ErrDryRun:
+		return true
+	}
+	return false
+}
+
+// retryBackoff is the wait before a trigger fires again after failures attempts which were not placed
+func retryBackoff(failures int) time.Duration {
+	wait := TriggerRetryInterval
+	for i := 1; i < failures && wait < TriggerMaxRetryInterval; i++ {
+		wait *= 2
+	}
+	if wait > TriggerMaxRetryInterval {
+		wait = TriggerMaxRetryInterval
+	}
+	return wait
+}
+
+// follow moves a trailing stop as the best price improves, reporting whether it moved
+func (t *Trigger) follow(price float64) bool {
+	if t.Best != 0 && ((t.Side == "sell" && price <= t.Best) || (t.Side == "buy" && price >= t.Best)) {
+		return false
+	}
+	t.Best = price
+	t.Price = price * (1 - t.Trail)
+	if t.Side == "buy" {
+		t.Price = price * (1 + t.Trail)
+	}
+	return true
+}
+
+// crossed reports whether the trigger condition is met at price
+func (t *Trigger) crossed(price float64) bool {
+	down := t.Side == "sell"
+	if t.Kind == TakeProfit {
+		down = !down
+	}
+	if down {
+		return price <= t.Price
+	}
+	return price >= t.Price
+}
+
+// fire places the order for a trigger
+func (e *Triggers) fire(t Trigger, price float64) (r TriggerResult) {
+	r.Trigger, r.Price, r.Rate = t, price, t.Rate
+	if r.Rate == 0 {
+		r.Rate = t.Price * (1 - t.Slippage)
+		if t.Side == "buy" {
+			r.Rate = t.Price * (1 + t.Slippage)
+		}
+	}
+	var b Buy
+	switch {
+	case t.Side == "buy" && t.ImmediateOrCancel:
+		b, r.Err = e.p.BuyImmediateOrCancel(t.Pair, r.Rate, t.Amount)
+	case t.Side == "buy":
+		b, r.Err = e.p.Buy(t.Pair, r.Rate, t.Amount)
+	case t.ImmediateOrCancel:
+		var s Sell
+		s, r.Err = e.p.SellImmediateOrCancel(t.Pair, r.Rate, t.Amount)
+		b = s.Buy
+	default:
+		var s Sell
+		s, r.Err = e.p.Sell(t.Pair, r.Rate, t.Amount)
+		b = s.Buy
+	}
+	r.OrderNumber, r.Trades = b.OrderNumber, b.ResultingTrades
+	return
+}
+
+// save writes the pending triggers to the file, replacing it atomically, the mutex must be held
+func (e *Triggers) save() error {
+	e.saved = time.Now()
+	if e.filename == "" {
+		e.dirty = false
+		return nil
+	}
+	tf := triggersFile{Seq: e.seq, Triggers: []*Trigger{}}
+	for _, t := range e.triggers {
+		tf.Triggers = append(tf.Triggers, t)
+	}
+	sort.Slice(tf.Triggers, func(i, j int) bool { return tf.Triggers[i].Created.Before(tf.Triggers[j].Created) })
+	b, err := json.MarshalIndent(tf, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal of triggers failed")
+	}
+	tmp := e.filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "writing "+tmp+" failed")
+	}
+	if err := os.Rename(tmp, e.filename); err != nil {
+		return errors.Wrap(err, "replacing "+e.filename+" failed")
+	}
+	e.dirty = false
+	return nil
+}
@@ -0,0 +1,56 @@
+package poloniex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func ExampleTriggers() {
+	defer func(d time.Duration) { TriggerRetryInterval = d }(TriggerRetryInterval)
+	TriggerRetryInterval = 30 * time.Millisecond
+
+	p := NewREST("key", "secret")
+	replies := []error{errors.Wrap(ErrHalted, "max loss"), nil, errors.New("request timed out")}
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			err := replies[0]
+			replies = replies[1:]
+			if err != nil {
+				return nil, err
+			}
+			return &Response{Status: 200, Body: `{"orderNumber":"202","resultingTrades":[]}`}, nil
+		}
+	})
+	e, _ := NewTriggers(p, "")
+	e.Start()
+	defer e.Stop()
+	p.On("trigger-fired", func(r TriggerResult) {
+		fmt.Println("fired", r.Trigger.ID, r.Rate, r.OrderNumber, r.Err, r.Failed)
+	})
+	e.TrailingStop("USDT_BTC", "sell", 0.05, 0.1)
+
+	// the first attempt is refused while trading is halted, so the stop stays pending
+	// and does not fire again until the backoff has passed
+	for _, last := range []float64{7000, 7200, 6800, 6790} {
+		p.Emit("ticker", WSTicker{Pair: "USDT_BTC", Last: last})
+	}
+	fmt.Println(len(e.Pending()), "pending")
+	time.Sleep(40 * time.Millisecond)
+	p.Emit("ticker", WSTicker{Pair: "USDT_BTC", Last: 6780})
+	fmt.Println(len(e.Pending()), "pending")
+
+	// a timeout may have placed the order, so the trigger is removed rather than fired twice
+	e.StopLoss("USDT_BTC", "sell", 6500, 0.1)
+	p.Emit("ticker", WSTicker{Pair: "USDT_BTC", Last: 6400})
+	p.Emit("ticker", WSTicker{Pair: "USDT_BTC", Last: 6300})
+	fmt.Println(len(e.Pending()), "pending")
+	// Output:
+	// fired 1 6840 0 max loss: trading is halted false
+	// 1 pending
+	// fired 1 6840 202 <nil> false
+	// 0 pending
+	// fired 2 6500 0 request timed out true
+	// 0 pending
+}
@@ -0,0 +1,222 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrWithdrawalsDisabled is returned by Withdraw once DisableWithdrawals has been called on the client
+var ErrWithdrawalsDisabled = errors.New("withdrawals are disabled")
+
+type (
+	// WithdrawalPolicy sets the checks a withdrawal must pass
+	WithdrawalPolicy struct {
+		// Allowlist maps a currency to the addresses it may be withdrawn to, currencies not listed cannot be withdrawn
+		Allowlist map[string][]string
+		// DailyLimits caps the amount of a currency withdrawn in any 24 hours, currencies not listed have no limit
+		DailyLimits map[string]float64
+		// MaxFee refuses a withdrawal when the currency's TxFee is more than this fraction of the amount, zero is no limit
+		MaxFee float64
+		// Approve is asked about every withdrawal which passes the checks, an error refuses it.
+		// It is called with the guard locked, so must not call the guard's methods.
+		Approve func(WithdrawalRequest) error
+		// Audit is given every attempt, allowed or not, including those refused by DisableWithdrawals, see AuditLog
+		Audit func(WithdrawalAttempt)
+	}
+
+	// WithdrawalRequest is a withdrawal waiting for approval
+	WithdrawalRequest struct {
+		Currency string
+		Amount   float64
+		Address  string
+		// TxFee is the exchange's fee for the withdrawal, taken from the amount
+		TxFee float64
+		// WithdrawnToday is the amount of the currency withdrawn in the last 24 hours
+		WithdrawnToday float64
+	}
+
+	// WithdrawalAttempt is the audit record of a withdrawal, it is also emitted as a "withdrawal-attempt" event
+	WithdrawalAttempt struct {
+		TS       time.Time
+		Currency string
+		Amount   float64
+		Address  string
+		Allowed  bool
+		Response string `json:",omitempty"`
+		Error    string `json:",omitempty"`
+	}
+
+	// WithdrawalGuard checks withdrawals against a policy before they are sent.
+	// Daily totals are kept in memory, so they start afresh when the process restarts.
+	WithdrawalGuard struct {
+		p       *Poloniex
+		policy  WithdrawalPolicy
+		mutex   sync.Mutex
+		history []WithdrawalAttempt
+	}
+)
+
+// GuardWithdrawals makes every Withdraw through the client pass the policy first, returning the guard
+func (p *Poloniex) GuardWithdrawals(policy WithdrawalPolicy) *WithdrawalGuard {
+	g := &WithdrawalGuard{p: p, policy: policy}
+	p.haltMutex.Lock()
+	defer p.haltMutex.Unlock()
+	p.withdrawals = g
+	return g
+}
+
+// DisableWithdrawals makes every Withdraw through the client fail with ErrWithdrawalsDisabled, it cannot be undone
+func (p *Poloniex) DisableWithdrawals() {
+	p.haltMutex.Lock()
+	defer p.haltMutex.Unlock()
+	p.noWithdrawals = true
+}
+
+// AuditLog returns an audit function writing each attempt to w as a line of JSON
+func AuditLog(w io.Writer) func(WithdrawalAttempt) {
+	mutex := sync.Mutex{}
+	enc := json.NewEncoder(w)
+	return func(a WithdrawalAttempt) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		enc.Encode(a)
+	}
+}
+
+// WithdrawnToday returns the amount of a currency withdrawn through the guard in the last 24 hours
+func (g *WithdrawalGuard) WithdrawnToday(currency string) float64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.withdrawnToday(currency)
+}
+
+// withdrawnToday totals the allowed withdrawals of the last 24 hours, the mutex must be held
+func (g *WithdrawalGuard) withdrawnToday(currency string) (total float64) {
+	since := time.Now().Add(-24 * time.Hour)
+	for _, a := range g.history {
+		if a.Currency == currency && a.TS.After(since) {
+			total += a.Amount
+		}
+	}
+	return
+}
+
+// withdraw checks a withdrawal against the policy and sends it if it passes, auditing the attempt either way
+func (g *WithdrawalGuard) withdraw(currency string, amount float64, address string) (w Withdraw, err error) {
+	attempt := WithdrawalAttempt{TS: time.Now(), Currency: currency, Amount: amount, Address: address}
+	w, err = g.send(&attempt)
+	// audited after the guard is released, so audit functions and listeners may call WithdrawnToday
+	g.p.auditWithdrawal(g.policy.Audit, attempt, err)
+	return
+}
+
+// send checks and sends the withdrawal of an attempt, filling in the outcome
+func (g *WithdrawalGuard) send(attempt *WithdrawalAttempt) (w Withdraw, err error) {
+	// the guard is held throughout so concurrent withdrawals cannot both fit under the daily limit
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	currency, amount, address := attempt.Currency, attempt.Amount, attempt.Address
+	req, err := g.check(currency, amount, address)
+	if err != nil {
+		return
+	}
+	if g.policy.Approve != nil {
+		if err = g.policy.Approve(req); err != nil {
+			err = errors.Wrap(err, "withdrawal was not approved")
+			return
+		}
+	}
+	attempt.Allowed = true
+	w, err = g.p.withdraw(currency, amount, address)
+	if err == nil && w.Error != "" {
+		err = errors.New(w.Error)
+	}
+	attempt.Response = w.Response
+	// a failed request may still have reached the exchange, so it counts against the limit
+	g.history = append(g.history, *attempt)
+	g.prune()
+	return
+}
+
+// check applies the allowlist, daily limit and fee checks, the mutex must be held
+func (g *WithdrawalGuard) check(currency string, amount float64, address string) (req WithdrawalRequest, err error) {
+	req = WithdrawalRequest{Currency: currency, Amount: amount, Address: address}
+	if amount <= 0 {
+		return req, errors.New("withdrawal amount must be positive")
+	}
+	allowed := false
+	for _, a := range g.policy.Allowlist[currency] {
+		if a == address {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return req, errors.Errorf("%s is not an allowed address for %s", address, currency)
+	}
+	req.WithdrawnToday = g.withdrawnToday(currency)
+	if limit, ok := g.policy.DailyLimits[currency]; ok && req.WithdrawnToday+amount > limit {
+		return req, errors.Errorf("withdrawal would take %s withdrawn today to %.8f, over the limit of %.8f",
+			currency, req.WithdrawnToday+amount, limit)
+	}
+	currencies, err := g.p.Currencies()
+	if err != nil {
+		return req, errors.Wrap(err, "fetching currencies failed")
+	}
+	c, ok := currencies[currency]
+	if !ok {
+		return req, errors.Errorf("unknown currency %s", currency)
+	}
+	if c.Disabled != 0 || c.Delisted != 0 || c.Frozen != 0 {
+		return req, errors.Errorf("%s is disabled, delisted or frozen", currency)
+	}
+	req.TxFee = c.TxFee
+	if amount <= c.TxFee {
+		return req, errors.Errorf("withdrawal of %.8f %s does not cover the fee of %.8f", amount, currency, c.TxFee)
+	}
+	if g.policy.MaxFee > 0 && c.TxFee/amount > g.policy.MaxFee {
+		return req, errors.Errorf("fee of %.8f %s is more than %.2f%% of the withdrawal", c.TxFee, currency, g.policy.MaxFee*100)
+	}
+	return req, nil
+}
+
+// withdrawUnguarded sends a withdrawal on a client without a guard, or refuses it if withdrawals are disabled,
+// and emits the attempt. audit is the guard's audit function, if there is one.
+func (p *Poloniex) withdrawUnguarded(currency string, amount float64, address string, disabled bool, audit func(WithdrawalAttempt)) (w Withdraw, err error) {
+	attempt := WithdrawalAttempt{TS: time.Now(), Currency: currency, Amount: amount, Address: address}
+	if disabled {
+		err = ErrWithdrawalsDisabled
+	} else {
+		attempt.Allowed = true
+		w, err = p.withdraw(currency, amount, address)
+		attempt.Response = w.Response
+		if err == nil && w.Error != "" {
+			attempt.Error = w.Error
+		}
+	}
+	p.auditWithdrawal(audit, attempt, err)
+	return
+}
+
+// auditWithdrawal records the error of an attempt, passes it to audit if there is one, and emits it
+func (p *Poloniex) auditWithdrawal(audit func(WithdrawalAttempt), a WithdrawalAttempt, err error) {
+	if err != nil {
+		a.Error = err.Error()
+	}
+	if audit != nil {
+		audit(a)
+	}
+	p.Emit("withdrawal-attempt", a)
+}
+
+// prune drops history older than the daily limit window, the mutex must be held
+func (g *WithdrawalGuard) prune() {
+	since := time.Now().Add(-24 * time.Hour)
+	for len(g.history) > 0 && !g.history[0].TS.After(since) {
+		g.history = g.history[1:]
+	}
+}
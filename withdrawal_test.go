@@ -0,0 +1,60 @@
+package poloniex
+
+import (
+	"fmt"
+)
+
+func ExampleWithdrawalGuard() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	p.Use(answer(map[string]string{
+		"returnCurrencies": `{"BTC":{"name":"Bitcoin","txFee":"0.0005","disabled":0,"delisted":0,"frozen":0}}`,
+		"withdraw":         `{"response":"Withdrew 0.60000000 BTC."}`,
+	}))
+	var g *WithdrawalGuard
+	g = p.GuardWithdrawals(WithdrawalPolicy{
+		Allowlist:   map[string][]string{"BTC": {"1BoatSLRHtKNngkdXEeobR76b53LETtpyT"}},
+		DailyLimits: map[string]float64{"BTC": 1},
+		// the guard is free again by the time attempts are audited
+		Audit: func(a WithdrawalAttempt) {
+			fmt.Println("audit:", a.Currency, a.Amount, a.Allowed, g.WithdrawnToday("BTC"))
+		},
+	})
+
+	// only allowlisted addresses, and no more than the daily limit
+	_, err := p.Withdraw("BTC", 0.6, "1Evil")
+	fmt.Println(err)
+	w, err := p.Withdraw("BTC", 0.6, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT")
+	fmt.Println(w.Response, err)
+	_, err = p.Withdraw("BTC", 0.6, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT")
+	fmt.Println(err)
+
+	p.DisableWithdrawals()
+	_, err = p.Withdraw("BTC", 0.1, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT")
+	fmt.Println(err)
+	// Output:
+	// audit: BTC 0.6 false 0
+	// 1Evil is not an allowed address for BTC
+	// audit: BTC 0.6 true 0.6
+	// Withdrew 0.60000000 BTC. <nil>
+	// audit: BTC 0.6 false 0.6
+	// withdrawal would take BTC withdrawn today to 1.20000000, over the limit of 1.00000000
+	// audit: BTC 0.1 false 0.6
+	// withdrawals are disabled
+}
+
+func ExamplePoloniex_Withdraw() {
+	p := NewREST("key", "secret")
+	p.SetRateLimit(1000, 1000)
+	p.Use(answer(map[string]string{"withdraw": `{"response":"Withdrew 0.10000000 BTC."}`}))
+	// withdrawals without a guard are still emitted
+	p.On("withdrawal-attempt", func(a WithdrawalAttempt) {
+		fmt.Printf("attempt: %s %v %v %q %q\n", a.Currency, a.Amount, a.Allowed, a.Response, a.Error)
+	})
+	p.Withdraw("BTC", 0.1, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT")
+	p.DisableWithdrawals()
+	p.Withdraw("BTC", 0.1, "1BoatSLRHtKNngkdXEeobR76b53LETtpyT")
+	// Output:
+	// attempt: BTC 0.1 true "Withdrew 0.10000000 BTC." ""
+	// attempt: BTC 0.1 false "" "withdrawals are disabled"
+}
@@ -0,0 +1,354 @@
+package poloniex
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// WorkingOrderConfig describes an order worked client side, as an iceberg, a pegged order, or both
+	WorkingOrderConfig struct {
+		Pair   string
+		Side   string
+		Amount float64
+		// Rate is the limit rate of an order which is not pegged
+		Rate float64
+		// Visible is the most shown on the book at once, the rest is held back and placed as each slice fills.
+		// Zero shows the whole amount.
+		Visible float64
+		// Peg keeps the order at the best bid for a buy, or the best ask for a sell, moved Offset away from the spread
+		Peg    bool
+		Offset float64
+		// LimitRate is the highest a pegged buy, or the lowest a pegged sell, will go, zero is no limit
+		LimitRate float64
+		// PostOnly places and moves slices as post only, pegged orders are always post only
+		PostOnly bool
+		// Interval is how often the order is checked against the book, defaults to a second
+		Interval time.Duration
+		// Poll is how often the REST API is polled for fills, defaults to 10 seconds.
+		// Fills are seen sooner when subscribed to account notifications.
+		Poll time.Duration
+		// Tolerance is how far the peg must move before the order is repriced
+		Tolerance float64
+		// Limiter bounds repricing, a reprice is skipped rather than queued when it is exhausted.
+		// Defaults to one reprice a second, share one limiter between working orders to bound them together.
+		Limiter *RateLimiter
+	}
+
+	// WorkingOrderProgress reports how far a working order has got, it is emitted as a "working-progress" event
+	WorkingOrderProgress struct {
+		Pair         string
+		Side         string
+		Target       float64
+		Filled       float64
+		Remaining    float64
+		AveragePrice float64
+		// Rate is the rate of the slice on the book
+		Rate     float64
+		Slices   []TrackedOrder
+		Reprices int
+		Done     bool
+	}
+
+	// WorkingOrder is an order worked client side by placing, replenishing and moving slices on the book
+	WorkingOrder struct {
+		p        *Poloniex
+		config   WorkingOrderConfig
+		book     *LiveBook
+		tracker  *OrderTracker
+		mutex    sync.Mutex
+		slices   []int64
+		final    []TrackedOrder
+		reprice  int
+		finished bool
+		cancel   context.CancelFunc
+		done     chan struct{}
+	}
+)
+
+// Iceberg places amount at rate showing at most visible at a time, replenishing the visible slice as it fills
+func (p *Poloniex) Iceberg(pair, side string, rate, amount, visible float64) (*WorkingOrder, error) {
+	return p.WorkingOrder(WorkingOrderConfig{Pair: pair, Side: side, Rate: rate, Amount: amount, Visible: visible})
+}
+
+// Peg places a post only order which follows the best bid (buy) or ask (sell) at offset, never beyond limitRate
+func (p *Poloniex) Peg(pair, side string, amount, offset, limitRate float64) (*WorkingOrder, error) {
+	return p.WorkingOrder(WorkingOrderConfig{Pair: pair, Side: side, Amount: amount, Peg: true, Offset: offset, LimitRate: limitRate})
+}
+
+// WorkingOrder starts working an order, pegged orders need the pair subscribed to for the live book
+func (p *Poloniex) WorkingOrder(config WorkingOrderConfig) (*WorkingOrder, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	w := &WorkingOrder{p: p, config: config, tracker: p.OrderTracker(), done: make(chan struct{})}
+	if config.Peg {
+		b, err := p.LiveBook(config.Pair)
+		if err != nil {
+			return nil, err
+		}
+		w.book = b
+	}
+	// the first slice is placed before returning so a bad order fails straight away
+	if err := w.replenish(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	go w.run(ctx)
+	return w, nil
+}
+
+func (c *WorkingOrderConfig) validate() error {
+	if c.Side != "buy" && c.Side != "sell" {
+		return errors.New("working order side must be buy or sell")
+	}
+	if c.Amount <= 0 {
+		return errors.New("working order amount must be positive")
+	}
+	if !c.Peg && c.Rate <= 0 {
+		return errors.New("working order rate must be positive")
+	}
+	if c.Visible <= 0 || c.Visible > c.Amount {
+		c.Visible = c.Amount
+	}
+	if c.Offset < 0 {
+		return errors.New("peg offset must not be negative")
+	}
+	if c.Peg {
+		c.PostOnly = true
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Poll <= 0 {
+		c.Poll = 10 * time.Second
+	}
+	if c.Limiter == nil {
+		c.Limiter = NewRateLimiter(1, 1)
+	}
+	return nil
+}
+
+// Cancel stops working the order and cancels the slice on the book, waiting for it to finish
+func (w *WorkingOrder) Cancel() WorkingOrderProgress {
+	w.cancel()
+	return w.Wait()
+}
+
+// Done is closed when the working order finishes
+func (w *WorkingOrder) Done() <-chan struct{} {
+	return w.done
+}
+
+// Wait waits for the working order to finish and returns the final progress
+func (w *WorkingOrder) Wait() WorkingOrderProgress {
+	<-w.done
+	return w.Progress()
+}
+
+// Progress reports how far the working order has got
+func (w *WorkingOrder) Progress() (pr WorkingOrderProgress) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	pr = WorkingOrderProgress{
+		Pair: w.config.Pair, Side: w.config.Side, Target: w.config.Amount, Reprices: w.reprice, Done: w.finished,
+	}
+	slices := w.final
+	if !w.finished {
+		slices = w.current()
+	}
+	total := 0.0
+	for _, o := range slices {
+		pr.Slices = append(pr.Slices, o)
+		pr.Filled += o.Filled
+		total += o.Total
+		if !o.State.Done() {
+			pr.Rate = o.Rate
+		}
+	}
+	pr.Remaining = math.Max(0, pr.Target-pr.Filled)
+	if pr.Filled > 0 {
+		pr.AveragePrice = total / pr.Filled
+	}
+	return
+}
+
+// current returns the state of the slices from the tracker, the mutex must be held
+func (w *WorkingOrder) current() []TrackedOrder {
+	slices := []TrackedOrder{}
+	for _, n := range w.slices {
+		if o, ok := w.tracker.Order(n); ok {
+			slices = append(slices, o)
+		}
+	}
+	return slices
+}
+
+func (w *WorkingOrder) run(ctx context.Context) {
+	defer close(w.done)
+	check := time.NewTicker(w.config.Interval)
+	defer check.Stop()
+	poll := time.NewTicker(w.config.Poll)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if o, ok := w.active(); ok {
+				if _, err := w.tracker.Cancel(o.OrderNumber); err != nil {
+					w.p.Emit("working-error", errors.Wrap(err, "cancelling slice failed"))
+				}
+			}
+			w.finish()
+			return
+		case <-poll.C:
+			if err := w.tracker.Poll(); err != nil {
+				w.p.Emit("working-error", err)
+			}
+			continue
+		case <-check.C:
+		}
+
+		o, ok := w.active()
+		if ok {
+			if w.config.Peg {
+				w.follow(o)
+			}
+			continue
+		}
+		if o.State == OrderCancelled {
+			// the slice was cancelled by someone else, or killed by the exchange
+			w.p.Emit("working-error", errors.Errorf("slice %d was cancelled", o.OrderNumber))
+			w.finish()
+			return
+		}
+		if w.Progress().Remaining <= filledEpsilon {
+			w.finish()
+			return
+		}
+		if err := w.replenish(); err != nil {
+			w.p.Emit("working-error", err)
+			continue
+		}
+		w.p.Emit("working-progress", w.Progress())
+	}
+}
+
+func (w *WorkingOrder) finish() {
+	w.mutex.Lock()
+	w.finished = true
+	// keep the final state of the slices and stop tracking them, the client's tracker outlives the working order
+	w.final = w.current()
+	for _, n := range w.slices {
+		w.tracker.Forget(n)
+	}
+	w.mutex.Unlock()
+	w.p.Emit("working-progress", w.Progress())
+}
+
+// active returns the latest slice, ok is false when it is done or there is none
+func (w *WorkingOrder) active() (o TrackedOrder, ok bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.slices) == 0 {
+		return o, false
+	}
+	o, found := w.tracker.Order(w.slices[len(w.slices)-1])
+	return o, found && !o.State.Done()
+}
+
+// replenish places the next visible slice
+func (w *WorkingOrder) replenish() error {
+	amount := math.Min(w.config.Visible, w.Progress().Remaining)
+	if amount <= filledEpsilon {
+		return nil
+	}
+	rate := w.config.Rate
+	if w.config.Peg {
+		var err error
+		if rate, err = w.target(TrackedOrder{}); err != nil {
+			return err
+		}
+	}
+	var b Buy
+	var err error
+	switch {
+	case w.config.Side == "buy" && w.config.PostOnly:
+		b, err = w.tracker.BuyPostOnly(w.config.Pair, rate, amount)
+	case w.config.Side == "buy":
+		b, err = w.tracker.Buy(w.config.Pair, rate, amount)
+	case w.config.PostOnly:
+		var s Sell
+		s, err = w.tracker.SellPostOnly(w.config.Pair, rate, amount)
+		b = s.Buy
+	default:
+		var s Sell
+		s, err = w.tracker.Sell(w.config.Pair, rate, amount)
+		b = s.Buy
+	}
+	if err != nil {
+		return errors.Wrap(err, "placing slice failed")
+	}
+	w.mutex.Lock()
+	w.slices = append(w.slices, b.OrderNumber)
+	w.mutex.Unlock()
+	return nil
+}
+
+// follow moves a pegged slice to the peg when it has moved past the tolerance and the limiter allows
+func (w *WorkingOrder) follow(o TrackedOrder) {
+	rate, err := w.target(o)
+	if err != nil {
+		w.p.Emit("working-error", err)
+		return
+	}
+	if math.Abs(rate-o.Rate) <= w.config.Tolerance || !w.config.Limiter.Allow() {
+		return
+	}
+	m, err := w.tracker.MovePostOnly(o.OrderNumber, rate)
+	if err != nil {
+		w.p.Emit("working-error", errors.Wrap(err, "moving slice failed"))
+		return
+	}
+	w.mutex.Lock()
+	w.slices[len(w.slices)-1] = m.OrderNumber
+	w.reprice++
+	w.mutex.Unlock()
+	w.p.Emit("working-progress", w.Progress())
+}
+
+// target returns the rate of the peg from the live book, held within the limit rate.
+// own is the slice on the book, which is left out so the peg does not follow itself.
+func (w *WorkingOrder) target(own TrackedOrder) (float64, error) {
+	side := w.book.Bids(2)
+	if w.config.Side == "sell" {
+		side = w.book.Asks(2)
+	}
+	if len(side) > 0 && side[0].Rate == own.Rate && side[0].Amount <= own.Remaining+filledEpsilon {
+		side = side[1:]
+	}
+	if len(side) == 0 {
+		return 0, errors.New("live book is empty")
+	}
+	rate := side[0].Rate - w.config.Offset
+	if w.config.Side == "sell" {
+		rate = side[0].Rate + w.config.Offset
+	}
+	if w.config.LimitRate > 0 {
+		if w.config.Side == "buy" {
+			rate = math.Min(rate, w.config.LimitRate)
+		} else {
+			rate = math.Max(rate, w.config.LimitRate)
+		}
+	}
+	// the API takes rates to 8 decimal places
+	rate = math.Round(rate*1e8) / 1e8
+	if rate <= 0 {
+		return 0, errors.New("peg rate is not positive")
+	}
+	return rate, nil
+}
@@ -0,0 +1,33 @@
+package poloniex
+
+import (
+	"fmt"
+	"log"
+)
+
+func ExampleWorkingOrder() {
+	p := NewREST("key", "secret")
+	p.Use(func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			switch req.Command {
+			case "buy":
+				return &Response{Status: 200, Body: `{"orderNumber":"101","resultingTrades":[]}`}, nil
+			case "cancelOrder":
+				return &Response{Status: 200, Body: `{"success":1}`}, nil
+			}
+			return nil, fmt.Errorf("unexpected %s", req.Command)
+		}
+	})
+	w, err := p.Iceberg("USDT_BTC", "buy", 7000, 1, 0.25)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	pr := w.Cancel()
+	fmt.Println(len(pr.Slices), pr.Slices[0].Amount, pr.Slices[0].State, pr.Remaining, pr.Done)
+	// the slices are no longer tracked once the working order has finished
+	_, tracked := p.OrderTracker().Order(101)
+	fmt.Println(tracked)
+	// Output:
+	// 1 0.25 cancelled 1 true
+	// false
+}
@@ -1,9 +1,17 @@
 package poloniex
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
 	turnpike "gopkg.in/beatgammit/turnpike.v2"
 )
 
+const (
+	accountChannel = "1000"
+)
+
 type (
 	subscription struct {
 		Command string `json:"command"`
@@ -12,10 +20,162 @@ type (
 
 	notificationSubscription struct {
 		subscription
-		Key string `json:"key"`
+		Key     string `json:"key"`
+		Payload string `json:"payload"`
+		Sign    string `json:"sign"`
+	}
+
+	// WSAccountOrder is a new order placed on your account, emitted as "account-new"
+	WSAccountOrder struct {
+		Pair           string
+		OrderNumber    int64
+		Type           string
+		Rate           float64
+		Amount         float64
+		StartingAmount float64
+		ClientOrderID  string
+		TS             time.Time
+	}
+
+	// WSAccountUpdate is a change to the amount of one of your orders, emitted as "account-update".
+	// Reason is "fill", "cancel" or "self-trade".
+	WSAccountUpdate struct {
+		OrderNumber   int64
+		Amount        float64
+		Reason        string
+		ClientOrderID string
+	}
+
+	// WSAccountTrade is a trade against one of your orders, emitted as "account-trade"
+	WSAccountTrade struct {
+		TradeID       int64
+		OrderNumber   int64
+		Rate          float64
+		Amount        float64
+		FeeMultiplier float64
+		TotalFee      float64
+		Total         float64
+		ClientOrderID string
+		TS            time.Time
+	}
+
+	// WSAccountKilled is an immediate-or-cancel or fill-or-kill order which was killed, emitted as "account-killed"
+	WSAccountKilled struct {
+		OrderNumber   int64
+		ClientOrderID string
+	}
+
+	// WSAccountBalance is a change to one of your balances, emitted as "account-balance"
+	WSAccountBalance struct {
+		CurrencyID int64
+		Wallet     string
+		Amount     float64
 	}
 )
 
+// SubscribeAccount subscribes to the private account notifications channel, which needs a key and secret.
+// Notifications are emitted as "account-new", "account-update", "account-trade", "account-killed" and "account-balance" events.
+func (p *Poloniex) SubscribeAccount() error {
+	c := p.getCredentials()
+	if c.Key == "" {
+		return errors.New("account notifications need a key and secret")
+	}
+	nonce, err := p.getNonce()
+	if err != nil {
+		return err
+	}
+	payload := "nonce=" + nonce
+	p.subscriptions[accountChannel] = true
+	message := notificationSubscription{
+		subscription: subscription{Command: "subscribe", Channel: accountChannel},
+		Key:          c.Key,
+		Payload:      payload,
+		Sign:         sign(c.Secret, payload),
+	}
+	return p.sendWSMessage(message)
+}
+
+// UnsubscribeAccount unsubscribes from the private account notifications channel
+func (p *Poloniex) UnsubscribeAccount() error {
+	delete(p.subscriptions, accountChannel)
+	return p.sendWSMessage(subscription{Command: "unsubscribe", Channel: accountChannel})
+}
+
+// handleAccount takes an account notification message and emits relevant events
+func (p *Poloniex) handleAccount(message []interface{}) error {
+	if len(message) < 3 {
+		// subscription acknowledgement
+		return nil
+	}
+	updates, ok := message[2].([]interface{})
+	if !ok {
+		return errors.New("cannot parse account notification")
+	}
+	for _, u := range updates {
+		v, ok := u.([]interface{})
+		if !ok || len(v) == 0 {
+			continue
+		}
+		field := func(i int) interface{} {
+			if i < len(v) {
+				return v[i]
+			}
+			return nil
+		}
+		num := func(i int) float64 {
+			if field(i) == nil {
+				return 0
+			}
+			return toFloat(field(i))
+		}
+		id := func(i int) int64 {
+			return int64(num(i))
+		}
+		str := func(i int) string {
+			s, _ := field(i).(string)
+			return s
+		}
+		switch v[0] {
+		case "n":
+			o := WSAccountOrder{
+				Pair:           p.ByID[fmt.Sprintf("%d", id(1))],
+				OrderNumber:    id(2),
+				Type:           "sell",
+				Rate:           num(4),
+				Amount:         num(5),
+				StartingAmount: num(7),
+				ClientOrderID:  str(8),
+			}
+			if id(3) == 1 {
+				o.Type = "buy"
+			}
+			o.TS, _ = parseTime(str(6))
+			p.Emit("account-new", o)
+		case "o":
+			reasons := map[string]string{"f": "fill", "c": "cancel", "s": "self-trade"}
+			p.Emit("account-update", WSAccountUpdate{OrderNumber: id(1), Amount: num(2), Reason: reasons[str(3)], ClientOrderID: str(4)})
+		case "t":
+			t := WSAccountTrade{
+				TradeID:       id(1),
+				Rate:          num(2),
+				Amount:        num(3),
+				FeeMultiplier: num(4),
+				OrderNumber:   id(6),
+				TotalFee:      num(7),
+				ClientOrderID: str(9),
+				Total:         num(10),
+			}
+			t.TS, _ = parseTime(str(8))
+			p.Emit("account-trade", t)
+		case "k":
+			p.Emit("account-killed", WSAccountKilled{OrderNumber: id(1), ClientOrderID: str(2)})
+		case "b":
+			p.Emit("account-balance", WSAccountBalance{CurrencyID: id(1), Wallet: str(2), Amount: num(3)})
+		}
+	}
+	return nil
+}
+
 func (p *Poloniex) sendWSMessage(msg interface{}) error {
 	p.ws.WriteJSON(msg)
 	return nil
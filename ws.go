@@ -2,11 +2,10 @@ package poloniex
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
-	"github.com/k0kubun/pp"
 	"github.com/pkg/errors"
 )
 
@@ -52,40 +51,117 @@ type (
 // StartWS opens the websocket connection, and waits for message events
 func (p *Poloniex) StartWS() {
 	ctx := context.Background()
+	// received and down track the connection, recws reconnects behind ReadMessage
+	received, down := false, false
 	for {
 		select {
 		case <-ctx.Done():
 			go p.ws.Close()
-			log.Printf("Websocket closed %s", p.ws.GetURL())
+			p.logger.Info("websocket closed", "url", p.ws.GetURL())
 			return
 		default:
-			message := []interface{}{}
-			if err := p.ws.ReadJSON(&message); err != nil {
-				log.Println(err)
+			_, frame, err := p.ws.ReadMessage()
+			if err != nil {
+				if !down {
+					p.logger.Warn("websocket read failed", "error", err)
+				}
+				down = true
 				continue
 			}
-			chid := int64(message[0].(float64)) // first element is the channel id
-			chids := toString(chid)
-			// we only handle informational and pair based channels, assuming the informational channels are orderbooks
-			if chid > 100.0 && chid < 1000.0 { //
-				if err := p.handleOrderBook(message); err != nil {
-					continue
-				}
-			} else if chids == p.ByName["ticker"] {
-				if err := p.handleTicker(message); err != nil {
-					continue
-				}
+			if down && received {
+				p.logger.Info("websocket reconnected")
+				p.metrics.Reconnect()
+				p.resetSequences()
+			}
+			received, down = true, false
+			ts := time.Now()
+			if err := p.record(ts, frame); err != nil {
+				p.logger.Error("recording websocket frame failed", "error", err)
 			}
+			if err := p.handleFrame(ts, frame); err != nil && err != ErrAck {
+				p.logger.Warn("handling websocket message failed", "error", err)
+			}
+		}
+	}
+}
+
+// handleFrame decodes a raw websocket frame received at ts and emits relevant events
+func (p *Poloniex) handleFrame(ts time.Time, frame []byte) (err error) {
+	_, span := p.tracer.Start(context.Background(), "poloniex.ws")
+	defer func() {
+		if err == ErrAck {
+			span.End()
+			return
 		}
+		endSpan(span, err)
+	}()
+
+	message := []interface{}{}
+	if err := json.Unmarshal(frame, &message); err != nil {
+		return err
+	}
+	if len(message) == 0 {
+		return errors.New("empty websocket message")
 	}
+	first, ok := message[0].(float64)
+	if !ok {
+		return errors.New("websocket message has no channel id")
+	}
+	chid := int64(first) // first element is the channel id
+	chids := toString(chid)
+	channel := p.channelName(chids)
+	span.SetAttribute("channel", channel)
+	p.metrics.WSMessage(channel)
+	// we only handle informational and pair based channels, assuming the informational channels are orderbooks
+	if chid > 100.0 && chid < 1000.0 { //
+		span.SetAttribute("pair", channel)
+		if len(message) > 1 && message[1] != nil {
+			p.checkSequence(channel, int64(toFloat(message[1])))
+		}
+		return p.handleOrderBook(ts, message)
+	} else if chids == p.ByName["ticker"] {
+		return p.handleTicker(message)
+	} else if chids == accountChannel {
+		return p.handleAccount(message)
+	}
+	return nil
+}
+
+// channelName returns the pair or name of a channel id, or the id if it is unknown
+func (p *Poloniex) channelName(chid string) string {
+	if chid == accountChannel {
+		return "account"
+	}
+	if name, ok := p.ByID[chid]; ok {
+		return name
+	}
+	return chid
+}
+
+// checkSequence reports a gap when a channel's sequence number is not one more than the last
+func (p *Poloniex) checkSequence(channel string, seq int64) {
+	p.seqMutex.Lock()
+	last, ok := p.sequences[channel]
+	p.sequences[channel] = seq
+	p.seqMutex.Unlock()
+	if ok && seq != last+1 {
+		p.logger.Warn("websocket sequence gap", "pair", channel, "expected", last+1, "got", seq)
+		p.metrics.SequenceGap(channel)
+	}
+}
+
+// resetSequences forgets the sequence numbers, which start again after a reconnect
+func (p *Poloniex) resetSequences() {
+	p.seqMutex.Lock()
+	defer p.seqMutex.Unlock()
+	p.sequences = map[string]int64{}
 }
 
 // takes a message and emits relevant events
-func (p *Poloniex) handleOrderBook(message []interface{}) error {
+func (p *Poloniex) handleOrderBook(ts time.Time, message []interface{}) error {
 	// it's an orderbook
-	orderbook, err := p.parseOrderbook(message)
+	orderbook, err := p.parseOrderbook(message, ts)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 	for _, v := range orderbook {
@@ -99,7 +175,6 @@ func (p *Poloniex) handleTicker(message []interface{}) error {
 	// it's a ticker
 	ticker, err := p.parseTicker(message)
 	if err != nil {
-		log.Printf("%s: (%s)\n", err, message)
 		return err
 	}
 	p.Emit("ticker", ticker)
@@ -148,7 +223,6 @@ func (p *Poloniex) Unsubscribe(chid string) error {
 // parse the ticker supplied
 func (p *Poloniex) parseTicker(raw []interface{}) (WSTicker, error) {
 	isAck := func(raw []interface{}) bool {
-		pp.Println(raw)
 		if raw[1] == nil {
 			return false
 		}
@@ -182,8 +256,8 @@ func (p *Poloniex) parseTicker(raw []interface{}) (WSTicker, error) {
 	return wt, nil
 }
 
-// parse the supplied orderbook
-func (p *Poloniex) parseOrderbook(raw []interface{}) ([]WSOrderbook, error) {
+// parse the supplied orderbook, ts is the time the message was received
+func (p *Poloniex) parseOrderbook(raw []interface{}, ts time.Time) ([]WSOrderbook, error) {
 	trades := []WSOrderbook{}
 	marketID := int64(toFloat(raw[0]))
 	pair, ok := p.ByID[fmt.Sprintf("%d", marketID)]
@@ -207,7 +281,7 @@ func (p *Poloniex) parseOrderbook(raw []interface{}) ([]WSOrderbook, error) {
 			}
 			trade.Rate = toFloat(v[2])
 			trade.Amount = toFloat(v[3])
-			trade.TS = time.Now()
+			trade.TS = ts
 		case "t":
 			trade.Event = "trade"
 			trade.TradeID = int64(toFloat(raw[1]))